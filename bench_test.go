@@ -1,6 +1,9 @@
 package xxhash
 
 import (
+	"bytes"
+	"fmt"
+	"math/rand"
 	"strings"
 	"testing"
 )
@@ -31,6 +34,21 @@ func BenchmarkSum64(b *testing.B) {
 	}
 }
 
+func BenchmarkSum64WithSeed(b *testing.B) {
+	for _, bb := range benchmarks {
+		in := make([]byte, bb.n)
+		for i := range in {
+			in[i] = byte(i)
+		}
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(bb.n)
+			for i := 0; i < b.N; i++ {
+				_ = Sum64WithSeed(in, 123)
+			}
+		})
+	}
+}
+
 func BenchmarkSum64String(b *testing.B) {
 	for _, bb := range benchmarks {
 		s := strings.Repeat("a", int(bb.n))
@@ -73,3 +91,260 @@ func BenchmarkDigestString(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkTemplate(b *testing.B) {
+	d := New()
+	d.WriteString(strings.Repeat("namespace/", 10))
+	tmpl := d.Template()
+	for _, bb := range benchmarks {
+		in := make([]byte, bb.n)
+		for i := range in {
+			in[i] = byte(i)
+		}
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(bb.n)
+			for i := 0; i < b.N; i++ {
+				_ = tmpl.Sum64(in)
+			}
+		})
+	}
+}
+
+func BenchmarkSum64Batch(b *testing.B) {
+	for _, n := range []int{4, 8, 16, 24, 64} {
+		keys := make([][]byte, 1000)
+		for i := range keys {
+			keys[i] = make([]byte, n)
+			for j := range keys[i] {
+				keys[i][j] = byte(i + j)
+			}
+		}
+		out := make([]uint64, len(keys))
+		b.Run(fmt.Sprintf("%dB", n), func(b *testing.B) {
+			b.SetBytes(int64(n * len(keys)))
+			for i := 0; i < b.N; i++ {
+				Sum64Batch(keys, out)
+			}
+		})
+		b.Run(fmt.Sprintf("%dB/loop", n), func(b *testing.B) {
+			b.SetBytes(int64(n * len(keys)))
+			for i := 0; i < b.N; i++ {
+				for j, k := range keys {
+					out[j] = Sum64(k)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSum64Uint64(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		sink = Sum64Uint64(uint64(i))
+	}
+}
+
+func BenchmarkDigestTypedWrites(b *testing.B) {
+	b.SetBytes(8 + 4 + 1 + 8)
+	for i := 0; i < b.N; i++ {
+		d := New()
+		d.WriteUint64(uint64(i))
+		d.WriteUint32(uint32(i))
+		d.WriteByte(1)
+		d.WriteFloat64(1.5)
+		sink = d.Sum64()
+	}
+}
+
+func BenchmarkSumReader(b *testing.B) {
+	for _, bb := range benchmarks {
+		in := make([]byte, bb.n)
+		for i := range in {
+			in[i] = byte(i)
+		}
+		r := bytes.NewReader(in)
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(bb.n)
+			for i := 0; i < b.N; i++ {
+				r.Reset(in)
+				sink, _ = SumReader(r)
+			}
+		})
+	}
+}
+
+func BenchmarkSum64Vec(b *testing.B) {
+	for _, bb := range benchmarks {
+		in := make([]byte, bb.n)
+		for i := range in {
+			in[i] = byte(i)
+		}
+		// Split the input into three uneven pieces, like prefix || key || suffix.
+		bufs := [][]byte{in[:bb.n/3], in[bb.n/3 : bb.n/2], in[bb.n/2:]}
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(bb.n)
+			for i := 0; i < b.N; i++ {
+				sink = Sum64Vec(bufs)
+			}
+		})
+	}
+}
+
+// BenchmarkDigestSmallWrites hashes 256 bytes of input split into many small
+// writes, as when a key is written field by field. The "random" case draws
+// write sizes uniformly from 1-31 bytes. Compare with
+// BenchmarkDigestSmallWrites/oneshot, which hashes the same bytes with Sum64.
+func BenchmarkDigestSmallWrites(b *testing.B) {
+	const total = 256
+	in := make([]byte, total)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	split := func(next func() int) [][]byte {
+		var pieces [][]byte
+		for rest := in; len(rest) > 0; {
+			n := next()
+			if n > len(rest) {
+				n = len(rest)
+			}
+			pieces = append(pieces, rest[:n])
+			rest = rest[n:]
+		}
+		return pieces
+	}
+	b.Run("oneshot", func(b *testing.B) {
+		b.SetBytes(total)
+		for i := 0; i < b.N; i++ {
+			sink = Sum64(in)
+		}
+	})
+	for _, size := range []int{1, 2, 4, 8, 16, 31} {
+		pieces := split(func() int { return size })
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			benchmarkWrites(b, pieces)
+		})
+	}
+	r := rand.New(rand.NewSource(1))
+	pieces := split(func() int { return 1 + r.Intn(31) })
+	b.Run("random", func(b *testing.B) {
+		benchmarkWrites(b, pieces)
+	})
+	strs := make([]string, len(pieces))
+	for i, p := range pieces {
+		strs[i] = string(p)
+	}
+	b.Run("random/string", func(b *testing.B) {
+		b.SetBytes(total)
+		var d Digest
+		for i := 0; i < b.N; i++ {
+			d.Reset()
+			for _, s := range strs {
+				d.WriteString(s)
+			}
+			sink = d.Sum64()
+		}
+	})
+}
+
+func benchmarkWrites(b *testing.B, pieces [][]byte) {
+	var n int64
+	for _, p := range pieces {
+		n += int64(len(p))
+	}
+	b.SetBytes(n)
+	var d Digest
+	for i := 0; i < b.N; i++ {
+		d.Reset()
+		for _, p := range pieces {
+			d.Write(p)
+		}
+		sink = d.Sum64()
+	}
+}
+
+// BenchmarkSum64Short covers each size class of the short-input (under 32
+// bytes) path of Sum64, which hashes with no block loop.
+func BenchmarkSum64Short(b *testing.B) {
+	in := make([]byte, 32)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	for _, n := range []int{0, 1, 2, 3, 4, 5, 7, 8, 12, 15, 16, 20, 24, 28, 31, 32} {
+		in := in[:n]
+		b.Run(fmt.Sprintf("%dB", n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for i := 0; i < b.N; i++ {
+				sink = Sum64(in)
+			}
+		})
+	}
+}
+
+// BenchmarkDigestSum64 measures finalizing a Digest, as when computing a
+// checksum per record of a stream.
+func BenchmarkDigestSum64(b *testing.B) {
+	in := make([]byte, 100)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	for _, n := range []int{0, 7, 31, 32, 36, 39, 63, 64, 96, 100} {
+		d := New()
+		d.Write(in[:n])
+		b.Run(fmt.Sprintf("%dB", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sink = d.Sum64()
+			}
+		})
+	}
+}
+
+// BenchmarkSum64Sizes sweeps input sizes from one block up to sizes that
+// exceed the caches, to show where the block loop's throughput levels off.
+func BenchmarkSum64Sizes(b *testing.B) {
+	const maxSize = 32 << 20
+	in := make([]byte, maxSize)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	for n := 32; n <= maxSize; n *= 4 {
+		in := in[:n]
+		b.Run(sizeName(n), func(b *testing.B) {
+			b.SetBytes(int64(n))
+			for i := 0; i < b.N; i++ {
+				sink = Sum64(in)
+			}
+		})
+	}
+}
+
+// BenchmarkSum64Alignment hashes inputs starting at each offset within a
+// 64-byte cache line. The block loop uses unaligned loads, so the offset
+// should make little difference.
+func BenchmarkSum64Alignment(b *testing.B) {
+	for _, n := range []int{100, 4 << 10, 1 << 20} {
+		// Large allocations are page-aligned, so buf[0] is at the start
+		// of a cache line.
+		buf := make([]byte, n+64+(8<<10))
+		for i := range buf {
+			buf[i] = byte(i)
+		}
+		for _, off := range []int{0, 1, 4, 8, 16, 31, 32, 63} {
+			in := buf[off : off+n]
+			b.Run(fmt.Sprintf("%s/off=%d", sizeName(n), off), func(b *testing.B) {
+				b.SetBytes(int64(n))
+				for i := 0; i < b.N; i++ {
+					sink = Sum64(in)
+				}
+			})
+		}
+	}
+}
+
+func sizeName(n int) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", n>>10)
+	}
+	return fmt.Sprintf("%dB", n)
+}
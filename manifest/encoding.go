@@ -0,0 +1,131 @@
+package manifest
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// ErrInvalidManifest is the error returned (possibly wrapped, for use with
+// errors.Is) when a Manifest is decoded from invalid input, and when a
+// Manifest with inconsistent fields is encoded or compared.
+var ErrInvalidManifest = errors.New("manifest: invalid manifest")
+
+// formatError describes why a Manifest was rejected.
+type formatError string
+
+func (e formatError) Error() string        { return ErrInvalidManifest.Error() + ": " + string(e) }
+func (e formatError) Is(target error) bool { return target == ErrInvalidManifest }
+
+// The binary form of a Manifest (format version 1) is laid out as follows,
+// with all integers in little-endian byte order:
+//
+//	magic     "xxm\x01"
+//	blockSize 8 bytes
+//	seed      8 bytes
+//	size      8 bytes
+//	sum       8 bytes
+//	blocks    8 bytes for each block; the number of blocks is determined by
+//	          size and blockSize
+//	checksum  8 bytes: xxhash.Sum64 of all of the preceding bytes
+const (
+	magic      = "xxm\x01"
+	headerSize = len(magic) + 8*4
+)
+
+// AppendBinary appends the binary form of m (see MarshalBinary) to b and
+// returns the extended slice. If m's fields are inconsistent, it returns b
+// unchanged and an error.
+func (m *Manifest) AppendBinary(b []byte) ([]byte, error) {
+	if err := m.check(); err != nil {
+		return b, err
+	}
+	start := len(b)
+	b = append(b, magic...)
+	b = appendUint64(b, uint64(m.BlockSize))
+	b = appendUint64(b, m.Seed)
+	b = appendUint64(b, uint64(m.Size))
+	b = appendUint64(b, uint64(m.Sum))
+	for _, h := range m.Blocks {
+		b = appendUint64(b, uint64(h))
+	}
+	b = appendUint64(b, xxhash.Sum64(b[start:]))
+	return b, nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The
+// binary form takes 8 bytes per block, plus 44 bytes, and includes a
+// checksum.
+func (m *Manifest) MarshalBinary() ([]byte, error) {
+	return m.AppendBinary(make([]byte, 0, headerSize+8*len(m.Blocks)+8))
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+//
+// If b is not a valid manifest, UnmarshalBinary returns an error for which
+// errors.Is(err, ErrInvalidManifest) is true, and m is unchanged.
+func (m *Manifest) UnmarshalBinary(b []byte) error {
+	if len(b) < headerSize+8 || string(b[:len(magic)]) != magic {
+		return formatError("unknown format")
+	}
+	sum := binary.LittleEndian.Uint64(b[len(b)-8:])
+	b = b[:len(b)-8]
+	if xxhash.Sum64(b) != sum {
+		return formatError("checksum mismatch")
+	}
+	b = b[len(magic):]
+	var x Manifest
+	var blockSize, size, h uint64
+	b, blockSize = consumeUint64(b)
+	b, x.Seed = consumeUint64(b)
+	b, size = consumeUint64(b)
+	b, h = consumeUint64(b)
+	x.Sum = xxhash.Hash(h)
+	if blockSize == 0 || blockSize > uint64(maxInt) || size > 1<<63-1 {
+		return formatError("invalid header")
+	}
+	x.BlockSize = int(blockSize)
+	x.Size = int64(size)
+	if numBlocks(x.Size, x.BlockSize) != int64(len(b)/8) || len(b)%8 != 0 {
+		return formatError("wrong size")
+	}
+	x.Blocks = make([]xxhash.Hash, len(b)/8)
+	for i := range x.Blocks {
+		b, h = consumeUint64(b)
+		x.Blocks[i] = xxhash.Hash(h)
+	}
+	*m = x
+	return nil
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// UnmarshalJSON implements the json.Unmarshaler interface. In addition to
+// decoding the fields, it checks that they are consistent, returning an
+// error for which errors.Is(err, ErrInvalidManifest) is true if they are
+// not. If it returns an error, m is unchanged.
+func (m *Manifest) UnmarshalJSON(b []byte) error {
+	type manifest Manifest // without the UnmarshalJSON method
+	var x manifest
+	if err := json.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	if err := (*Manifest)(&x).check(); err != nil {
+		return err
+	}
+	*m = Manifest(x)
+	return nil
+}
+
+func appendUint64(b []byte, x uint64) []byte {
+	var a [8]byte
+	binary.LittleEndian.PutUint64(a[:], x)
+	return append(b, a[:]...)
+}
+
+func consumeUint64(b []byte) ([]byte, uint64) {
+	x := binary.LittleEndian.Uint64(b)
+	return b[8:], x
+}
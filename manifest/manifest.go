@@ -0,0 +1,214 @@
+// Package manifest computes block manifests: lists of the XXH64 hashes of the
+// fixed-size blocks of a stream, along with the hash of the whole stream.
+//
+// Manifests are the building blocks of deduplication and rsync-style delta
+// transfers. A receiver that holds an old version of a file sends its
+// manifest; the sender builds a manifest of the new version with the same
+// block size and seed, and Diff reports which blocks need to be sent.
+//
+// A manifest is built in a single pass over its stream, with Build or with a
+// Builder, which is an io.Writer. It can be stored in a compact binary form
+// (see Manifest.MarshalBinary) or as JSON.
+package manifest
+
+import (
+	"errors"
+	"io"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// A Manifest describes a stream of Size bytes divided into blocks of
+// BlockSize bytes. Every block except the last is exactly BlockSize bytes
+// long; the last holds the remaining 1 to BlockSize bytes. An empty stream
+// has no blocks.
+//
+// The hashes are XXH64 hashes computed with Seed: Blocks[i] is the hash of
+// block i, and Sum is the hash of the whole stream, so with a zero Seed it is
+// equal to xxhash.Sum64 of the stream's contents.
+//
+// A Manifest is encoded to JSON as an object with the fields "blockSize",
+// "seed" (a decimal string, since seeds can exceed the range that JSON
+// numbers represent exactly), "size", "sum", and "blocks", where the hashes
+// are strings of 16 hexadecimal digits (see xxhash.Hash).
+type Manifest struct {
+	BlockSize int           `json:"blockSize"`
+	Seed      uint64        `json:"seed,string"`
+	Size      int64         `json:"size"`
+	Sum       xxhash.Hash   `json:"sum"`
+	Blocks    []xxhash.Hash `json:"blocks"`
+}
+
+// A Block describes one block of a stream.
+type Block struct {
+	Offset int64
+	Length int
+	Hash   xxhash.Hash
+}
+
+// Block returns the offset, length, and hash of block i of m. It panics if i
+// is out of range.
+func (m *Manifest) Block(i int) Block {
+	off := int64(i) * int64(m.BlockSize)
+	n := m.Size - off
+	if n > int64(m.BlockSize) {
+		n = int64(m.BlockSize)
+	}
+	return Block{Offset: off, Length: int(n), Hash: m.Blocks[i]}
+}
+
+// numBlocks returns the number of blocks in a stream of size bytes.
+func numBlocks(size int64, blockSize int) int64 {
+	n := size / int64(blockSize)
+	if size%int64(blockSize) != 0 {
+		n++
+	}
+	return n
+}
+
+// check reports whether the fields of m are consistent.
+func (m *Manifest) check() error {
+	if m.BlockSize <= 0 {
+		return formatError("block size is not positive")
+	}
+	if m.Size < 0 {
+		return formatError("negative size")
+	}
+	if numBlocks(m.Size, m.BlockSize) != int64(len(m.Blocks)) {
+		return formatError("wrong number of blocks")
+	}
+	return nil
+}
+
+// Build reads r until EOF and returns the manifest of the data it read,
+// divided into blocks of blockSize bytes and hashed with a zero seed. It
+// panics if blockSize is not positive.
+//
+// If reading fails, Build returns the error (other than io.EOF) and a nil
+// Manifest.
+func Build(r io.Reader, blockSize int) (*Manifest, error) {
+	return BuildWithSeed(r, blockSize, 0)
+}
+
+// BuildWithSeed is like Build but hashes the blocks and the whole stream with
+// the given seed.
+func BuildWithSeed(r io.Reader, blockSize int, seed uint64) (*Manifest, error) {
+	b := NewBuilder(blockSize, seed)
+	buf := make([]byte, readBufSize)
+	for {
+		n, err := r.Read(buf)
+		b.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.Manifest(), nil
+}
+
+// readBufSize is the size of the buffer that BuildWithSeed reads into.
+const readBufSize = 256 << 10
+
+// A Builder computes a manifest of the data written to it. Each byte is
+// hashed twice (once for its block and once for the whole stream) but read
+// only once, so a Builder can be fed by an io.MultiWriter or io.TeeReader
+// while the data is being stored or sent elsewhere.
+//
+// A Builder must be created with NewBuilder.
+type Builder struct {
+	m     Manifest
+	block xxhash.Digest // the current, incomplete block
+	n     int           // how much of the current block has been written
+	whole xxhash.Digest
+}
+
+// NewBuilder returns a Builder that divides its input into blocks of
+// blockSize bytes and hashes them with the given seed. It panics if blockSize
+// is not positive.
+func NewBuilder(blockSize int, seed uint64) *Builder {
+	if blockSize <= 0 {
+		panic("manifest: invalid block size")
+	}
+	b := &Builder{m: Manifest{BlockSize: blockSize, Seed: seed}}
+	b.block.ResetWithSeed(seed)
+	b.whole.ResetWithSeed(seed)
+	return b
+}
+
+// Write adds more data to the stream. It always returns len(p), nil.
+func (b *Builder) Write(p []byte) (n int, err error) {
+	n = len(p)
+	b.m.Size += int64(n)
+	b.whole.Write(p)
+	c := b.m.BlockSize
+	if b.n > 0 {
+		// Complete the current block first.
+		k := c - b.n
+		if k > len(p) {
+			k = len(p)
+		}
+		b.block.Write(p[:k])
+		b.n += k
+		p = p[k:]
+		if b.n < c {
+			return
+		}
+		b.m.Blocks = append(b.m.Blocks, xxhash.Hash(b.block.Sum64()))
+		b.block.ResetWithSeed(b.m.Seed)
+		b.n = 0
+	}
+	for len(p) >= c {
+		b.m.Blocks = append(b.m.Blocks, xxhash.Hash(xxhash.Sum64WithSeed(p[:c], b.m.Seed)))
+		p = p[c:]
+	}
+	if len(p) > 0 {
+		b.block.Write(p)
+		b.n = len(p)
+	}
+	return
+}
+
+// Manifest returns the manifest of the data written so far. The Builder can
+// continue to be used afterwards.
+func (b *Builder) Manifest() *Manifest {
+	m := b.m
+	m.Blocks = make([]xxhash.Hash, len(b.m.Blocks), len(b.m.Blocks)+1)
+	copy(m.Blocks, b.m.Blocks)
+	if b.n > 0 {
+		m.Blocks = append(m.Blocks, xxhash.Hash(b.block.Sum64()))
+	}
+	m.Sum = xxhash.Hash(b.whole.Sum64())
+	return &m
+}
+
+// Diff returns, in increasing order, the indexes of the blocks of b that
+// differ from the block at the same index of a, including the blocks of b
+// that are past the end of a. To turn a's stream into b's, it suffices to
+// replace those blocks and then truncate the result to b.Size bytes.
+//
+// If a and b have different block sizes or seeds, their blocks cannot be
+// compared, and Diff returns an error. It also returns an error if either
+// manifest's fields are inconsistent.
+func Diff(a, b *Manifest) ([]int, error) {
+	if err := a.check(); err != nil {
+		return nil, err
+	}
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+	if a.BlockSize != b.BlockSize {
+		return nil, errors.New("manifest: cannot compare manifests with different block sizes")
+	}
+	if a.Seed != b.Seed {
+		return nil, errors.New("manifest: cannot compare manifests with different seeds")
+	}
+	var diff []int
+	for i := range b.Blocks {
+		if i >= len(a.Blocks) || a.Block(i) != b.Block(i) {
+			diff = append(diff, i)
+		}
+	}
+	return diff, nil
+}
@@ -0,0 +1,293 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+func testInput(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i*7 + i>>8)
+	}
+	return b
+}
+
+// reference computes the manifest of b directly from its definition.
+func reference(b []byte, blockSize int, seed uint64) *Manifest {
+	m := &Manifest{
+		BlockSize: blockSize,
+		Seed:      seed,
+		Size:      int64(len(b)),
+		Sum:       xxhash.Hash(xxhash.Sum64WithSeed(b, seed)),
+		Blocks:    []xxhash.Hash{},
+	}
+	for p := b; len(p) > 0; {
+		n := blockSize
+		if n > len(p) {
+			n = len(p)
+		}
+		m.Blocks = append(m.Blocks, xxhash.Hash(xxhash.Sum64WithSeed(p[:n], seed)))
+		p = p[n:]
+	}
+	return m
+}
+
+func TestBuild(t *testing.T) {
+	for _, blockSize := range []int{1, 7, 32, 1000, 4096} {
+		for _, n := range []int{0, 1, 6, 7, 8, 999, 1000, 1001, 10000, 300 << 10} {
+			for _, seed := range []uint64{0, 1, 1 << 63} {
+				b := testInput(n)
+				want := reference(b, blockSize, seed)
+				var got *Manifest
+				var err error
+				if seed == 0 {
+					got, err = Build(bytes.NewReader(b), blockSize)
+				} else {
+					got, err = BuildWithSeed(bytes.NewReader(b), blockSize, seed)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("Build(%d bytes, %d, seed %d):\ngot  %+v\nwant %+v", n, blockSize, seed, got, want)
+				}
+			}
+		}
+	}
+	// A zero-seed manifest's Sum is the XXH64 of the stream.
+	b := testInput(5000)
+	m, err := Build(iotest.OneByteReader(bytes.NewReader(b)), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := uint64(m.Sum), xxhash.Sum64(b); got != want {
+		t.Fatalf("Sum = %#x; want %#x", got, want)
+	}
+}
+
+func TestBuildError(t *testing.T) {
+	errTest := errors.New("test error")
+	r := io.MultiReader(bytes.NewReader(testInput(100)), iotest.ErrReader(errTest))
+	m, err := Build(r, 16)
+	if err != errTest || m != nil {
+		t.Fatalf("Build: got (%v, %v); want (nil, %v)", m, err, errTest)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	b := testInput(10000)
+	for _, blockSize := range []int{1, 100, 1024} {
+		want := reference(b, blockSize, 5)
+		for _, size := range []int{1, 3, 99, 100, 101, 2500, len(b)} {
+			bl := NewBuilder(blockSize, 5)
+			for p := b; len(p) > 0; {
+				n := size
+				if n > len(p) {
+					n = len(p)
+				}
+				bl.Write(p[:n])
+				p = p[n:]
+				// Manifest must not disturb the Builder.
+				bl.Manifest()
+			}
+			if got := bl.Manifest(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("block size %d, writes of %d:\ngot  %+v\nwant %+v", blockSize, size, got, want)
+			}
+		}
+	}
+}
+
+func TestBlock(t *testing.T) {
+	m := reference(testInput(2500), 1000, 0)
+	for i, want := range []Block{
+		{0, 1000, m.Blocks[0]},
+		{1000, 1000, m.Blocks[1]},
+		{2000, 500, m.Blocks[2]},
+	} {
+		if got := m.Block(i); got != want {
+			t.Errorf("Block(%d) = %+v; want %+v", i, got, want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	const blockSize = 100
+	old := testInput(1050)
+	for _, tt := range []struct {
+		name string
+		new  func() []byte
+		want []int
+	}{
+		{"same", func() []byte { return old }, nil},
+		{"changed", func() []byte {
+			b := append([]byte(nil), old...)
+			b[250]++
+			b[999]++
+			return b
+		}, []int{2, 9}},
+		{"appended", func() []byte { return append(append([]byte(nil), old...), testInput(200)...) }, []int{10, 11, 12}},
+		{"truncated in a block", func() []byte { return old[:420] }, []int{4}},
+		{"truncated at a boundary", func() []byte { return old[:400] }, nil},
+		{"empty", func() []byte { return nil }, nil},
+		{"from empty", func() []byte { return old[:150] }, []int{0, 1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := reference(old, blockSize, 9)
+			if tt.name == "from empty" {
+				a = reference(nil, blockSize, 9)
+			}
+			b := reference(tt.new(), blockSize, 9)
+			got, err := Diff(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Diff = %v; want %v", got, tt.want)
+			}
+		})
+	}
+
+	a := reference(old, blockSize, 0)
+	if _, err := Diff(a, reference(old, blockSize+1, 0)); err == nil {
+		t.Error("Diff with different block sizes: got no error")
+	}
+	if _, err := Diff(a, reference(old, blockSize, 1)); err == nil {
+		t.Error("Diff with different seeds: got no error")
+	}
+	bad := reference(old, blockSize, 0)
+	bad.Blocks = bad.Blocks[1:]
+	if _, err := Diff(a, bad); !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("Diff with an inconsistent manifest: got error %v; want ErrInvalidManifest", err)
+	}
+}
+
+func TestBinary(t *testing.T) {
+	m := reference([]byte("hello, world"), 5, 7)
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The format must not change.
+	const want = "78786d01" + // magic
+		"0500000000000000" + // block size
+		"0700000000000000" + // seed
+		"0c00000000000000" + // size
+		"dc1a210177fb03ab" + // sum
+		"2e4ec38b5f3129d3" + "1a99296e4b5bb0aa" + "33ed3474bb21a097" + // blocks
+		"48a01d993e5c3e80" // checksum
+	if got := hex.EncodeToString(b); got != want {
+		t.Fatalf("MarshalBinary:\ngot  %s\nwant %s", got, want)
+	}
+	if got, want := uint64(m.Sum), xxhash.Sum64WithSeed([]byte("hello, world"), 7); got != want {
+		t.Fatalf("Sum = %#x; want %#x", got, want)
+	}
+
+	for _, n := range []int{0, 1, 5000} {
+		m := reference(testInput(n), 64, 3)
+		b, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != 44+8*len(m.Blocks) {
+			t.Fatalf("len(MarshalBinary()) = %d; want %d", len(b), 44+8*len(m.Blocks))
+		}
+		var got Manifest
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(&got, m) {
+			t.Fatalf("round trip of %d bytes:\ngot  %+v\nwant %+v", n, &got, m)
+		}
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	m := reference(testInput(1000), 64, 3)
+	good, _ := m.MarshalBinary()
+	for _, tt := range []struct {
+		name string
+		b    []byte
+	}{
+		{"empty", nil},
+		{"bad magic", append([]byte("xxm\x02"), good[4:]...)},
+		{"truncated", good[:len(good)-1]},
+		{"corrupted", func() []byte {
+			b := append([]byte(nil), good...)
+			b[50] ^= 1
+			return b
+		}()},
+		{"extra block", func() []byte {
+			b := append([]byte(nil), good[:len(good)-8]...)
+			b = appendUint64(b, 0)
+			return appendUint64(b, xxhash.Sum64(b))
+		}()},
+		{"zero block size", func() []byte {
+			b := append([]byte(nil), good[:len(good)-8]...)
+			copy(b[4:], make([]byte, 8))
+			return appendUint64(b, xxhash.Sum64(b))
+		}()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := *m
+			if err := got.UnmarshalBinary(tt.b); !errors.Is(err, ErrInvalidManifest) {
+				t.Fatalf("got error %v; want ErrInvalidManifest", err)
+			}
+			if !reflect.DeepEqual(&got, m) {
+				t.Fatal("UnmarshalBinary changed the Manifest on error")
+			}
+		})
+	}
+
+	bad := *m
+	bad.Blocks = nil
+	if _, err := bad.MarshalBinary(); !errors.Is(err, ErrInvalidManifest) {
+		t.Fatalf("MarshalBinary of an inconsistent manifest: got error %v; want ErrInvalidManifest", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	m := &Manifest{
+		BlockSize: 4,
+		Seed:      1 << 63,
+		Size:      6,
+		Sum:       0x0123456789abcdef,
+		Blocks:    []xxhash.Hash{0xfedcba9876543210, 1},
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"blockSize":4,"seed":"9223372036854775808","size":6,"sum":"0123456789abcdef","blocks":["fedcba9876543210","0000000000000001"]}`
+	if string(b) != want {
+		t.Fatalf("json.Marshal:\ngot  %s\nwant %s", b, want)
+	}
+	var got Manifest
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, m) {
+		t.Fatalf("round trip:\ngot  %+v\nwant %+v", &got, m)
+	}
+
+	for _, s := range []string{
+		`{"blockSize":4,"seed":"0","size":9,"sum":"0123456789abcdef","blocks":["fedcba9876543210","0000000000000001"]}`,
+		`{"blockSize":0,"seed":"0","size":0,"sum":"0123456789abcdef","blocks":[]}`,
+		`{"blockSize":4,"seed":"0","size":-1,"sum":"0123456789abcdef","blocks":[]}`,
+	} {
+		if err := json.Unmarshal([]byte(s), &got); !errors.Is(err, ErrInvalidManifest) {
+			t.Errorf("json.Unmarshal(%s): got error %v; want ErrInvalidManifest", s, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"blockSize":4,"seed":"0","size":1,"sum":"0123","blocks":["fedcba9876543210"]}`), &got); err == nil {
+		t.Error("json.Unmarshal with a short hash: got no error")
+	}
+}
@@ -6,5 +6,15 @@ set -eu -o pipefail
 
 go test ./...
 go test -tags purego ./...
-GOARCH=arm64 go test
-GOARCH=arm64 go test -tags purego
+GOARCH=arm64 go test . ./xxh3 ./xxh32
+GOARCH=arm64 go test -tags purego . ./xxh3 ./xxh32
+GOARCH=riscv64 go test . ./xxh3 ./xxh32
+GOARCH=riscv64 go test -tags purego .
+GOARCH=ppc64le go test . ./xxh3 ./xxh32
+GOARCH=ppc64le go test -tags purego .
+# s390x is big-endian, so this also checks that the Go code makes no
+# assumptions about byte order.
+GOARCH=s390x go test . ./xxh3 ./xxh32
+GOARCH=s390x go test -tags purego .
+go test -tags appengine .
+GOARCH=386 go test .
@@ -0,0 +1,56 @@
+package tree
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+var benchmarks = []struct {
+	name string
+	n    int64
+}{
+	{"1MB", 1 << 20},
+	{"64MB", 64 << 20},
+}
+
+func BenchmarkSum64(b *testing.B) {
+	for _, bb := range benchmarks {
+		in := specInput(int(bb.n))
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(bb.n)
+			for i := 0; i < b.N; i++ {
+				_ = Sum64(in)
+			}
+		})
+	}
+}
+
+// BenchmarkXXH64 hashes the same inputs as BenchmarkSum64 sequentially, for
+// comparison.
+func BenchmarkXXH64(b *testing.B) {
+	for _, bb := range benchmarks {
+		in := specInput(int(bb.n))
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(bb.n)
+			for i := 0; i < b.N; i++ {
+				_ = xxhash.Sum64(in)
+			}
+		})
+	}
+}
+
+func BenchmarkSumReaderAt(b *testing.B) {
+	for _, bb := range benchmarks {
+		r := bytes.NewReader(specInput(int(bb.n)))
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(bb.n)
+			for i := 0; i < b.N; i++ {
+				if _, err := SumReaderAt(r, bb.n); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
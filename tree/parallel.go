@@ -0,0 +1,190 @@
+package tree
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	// minWorkerBytes is the least amount of data worth handing to a
+	// goroutine of its own.
+	minWorkerBytes = 64 << 10
+
+	// maxBatch is the most chunks that Digest.Write hashes at once.
+	maxBatch = 1024
+
+	// readBufSize is the largest buffer that SumReaderAt reads a chunk into;
+	// larger chunks are read in pieces.
+	readBufSize = 1 << 20
+
+	// windowBytes is the least amount of data that each goroutine of
+	// SumReaderAt hashes between synchronizations.
+	windowBytes = 4 << 20
+)
+
+// numWorkers returns the number of goroutines to use for hashing n chunks
+// that total size bytes.
+func numWorkers(n, size int) int {
+	w := runtime.GOMAXPROCS(0)
+	if m := size / minWorkerBytes; m < w {
+		w = m
+	}
+	if n < w {
+		w = n
+	}
+	return w
+}
+
+// sumChunks returns the leaf digests of the chunks of b, which must be a
+// whole number of chunks, computed by the given number of goroutines.
+func sumChunks(b []byte, chunkSize, workers int) []uint64 {
+	leaves := make([]uint64, len(b)/chunkSize)
+	parallel(len(leaves), workers, func(_, i int) bool {
+		leaves[i] = xxhash.Sum64(b[i*chunkSize : (i+1)*chunkSize])
+		return true
+	})
+	return leaves
+}
+
+// parallel calls f(w, i) for each i in [0, n) from the given number of
+// goroutines, where w (in [0, workers)) identifies the calling goroutine. It
+// stops early once any call returns false.
+func parallel(n, workers int, f func(w, i int) bool) {
+	var next int64
+	var stop int32
+	work := func(w int) {
+		for atomic.LoadInt32(&stop) == 0 {
+			i := int(atomic.AddInt64(&next, 1) - 1)
+			if i >= n {
+				return
+			}
+			if !f(w, i) {
+				atomic.StoreInt32(&stop, 1)
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	for w := 1; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			work(w)
+		}(w)
+	}
+	work(0)
+	wg.Wait()
+}
+
+// SumReaderAt computes the tree hash, with the default chunk size, of the
+// first size bytes of r. It reads and hashes chunks in parallel, using up to
+// GOMAXPROCS goroutines.
+//
+// If r has fewer than size bytes, SumReaderAt returns io.ErrUnexpectedEOF.
+func SumReaderAt(r io.ReaderAt, size int64) (uint64, error) {
+	return SumReaderAtWithChunkSize(r, size, DefaultChunkSize)
+}
+
+// SumReaderAtWithChunkSize is like SumReaderAt but uses the given chunk
+// size. It panics if chunkSize is not a power of two between MinChunkSize
+// and MaxChunkSize.
+func SumReaderAtWithChunkSize(r io.ReaderAt, size int64, chunkSize int) (uint64, error) {
+	checkChunkSize(chunkSize)
+	if size < 0 {
+		return 0, errors.New("tree: negative size")
+	}
+	c := int64(chunkSize)
+	nchunks := (size + c - 1) / c
+	workers := runtime.GOMAXPROCS(0)
+	if int64(workers) > nchunks {
+		workers = int(nchunks)
+	}
+	bufSize := chunkSize
+	if bufSize > readBufSize {
+		bufSize = readBufSize
+	}
+	bufs := make([][]byte, workers)
+	errs := make([]error, workers)
+
+	// Chunks are hashed in windows of at least a few (and at least
+	// windowBytes) per goroutine, and the leaf digests of each window are
+	// added to the root message once they are all known.
+	perWorker := int64(16)
+	if k := windowBytes / c; k > perWorker {
+		perWorker = k
+	}
+	window := int64(workers) * perWorker
+	if window > nchunks {
+		window = nchunks
+	}
+	leaves := make([]uint64, window)
+	var root xxhash.Digest
+	for start := int64(0); start < nchunks; start += window {
+		m := nchunks - start
+		if m > window {
+			m = window
+		}
+		parallel(int(m), workers, func(w, i int) bool {
+			if bufs[w] == nil {
+				bufs[w] = make([]byte, bufSize)
+			}
+			off := (start + int64(i)) * c
+			n := size - off
+			if n > c {
+				n = c
+			}
+			leaves[i], errs[w] = sumSection(r, bufs[w], off, n)
+			return errs[w] == nil
+		})
+		for _, err := range errs {
+			if err != nil {
+				return 0, err
+			}
+		}
+		for _, h := range leaves[:m] {
+			root.WriteUint64(h)
+		}
+	}
+	return finish(&root, uint64(size), chunkSize), nil
+}
+
+// sumSection returns the XXH64 digest of the n bytes of r at off, reading
+// them through buf.
+func sumSection(r io.ReaderAt, buf []byte, off, n int64) (uint64, error) {
+	if n <= int64(len(buf)) {
+		if err := readFullAt(r, buf[:n], off); err != nil {
+			return 0, err
+		}
+		return xxhash.Sum64(buf[:n]), nil
+	}
+	var d xxhash.Digest
+	for n > 0 {
+		k := int64(len(buf))
+		if k > n {
+			k = n
+		}
+		if err := readFullAt(r, buf[:k], off); err != nil {
+			return 0, err
+		}
+		d.Write(buf[:k])
+		off += k
+		n -= k
+	}
+	return d.Sum64(), nil
+}
+
+// readFullAt reads exactly len(p) bytes of r at off into p.
+func readFullAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
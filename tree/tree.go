@@ -0,0 +1,201 @@
+// Package tree implements a tree-hashing mode for XXH64 that can use every
+// core of a machine to hash a single large input.
+//
+// XXH64 processes its input strictly in order, so hashing one large file
+// with it runs on a single core. The tree hash instead splits the input into
+// fixed-size chunks, hashes each chunk with XXH64 (which can be done in
+// parallel), and then hashes the list of chunk digests, together with the
+// input length and chunk size, to produce the root digest.
+//
+// The tree hash of an input is a different value from its XXH64 hash, and it
+// depends on the chunk size; both sides of a comparison must use the same
+// chunk size. The construction is versioned (see Version), and the root
+// digest will never change for a given version and chunk size. SPEC.md in
+// this package's directory defines it precisely enough to implement in other
+// languages, and includes test vectors.
+//
+// Like XXH64 itself, the tree hash is not a cryptographic hash.
+package tree
+
+import (
+	"github.com/cespare/xxhash/v2"
+)
+
+// Version is the version of the tree construction implemented by this
+// package. It is encoded in the last byte of the root message.
+const Version = 1
+
+// magic ends every root message: "xxhtree" followed by the version byte.
+const magic = "xxhtree\x01"
+
+const (
+	// DefaultChunkSize is the chunk size used by Sum64, SumReaderAt, New,
+	// and the zero Digest.
+	DefaultChunkSize = 1 << 20
+
+	// MinChunkSize and MaxChunkSize are the smallest and largest valid chunk
+	// sizes. A chunk size must also be a power of two.
+	MinChunkSize = 1 << 10
+	MaxChunkSize = 1 << 30
+)
+
+// checkChunkSize panics if chunkSize is not a valid chunk size.
+func checkChunkSize(chunkSize int) {
+	if chunkSize < MinChunkSize || chunkSize > MaxChunkSize || chunkSize&(chunkSize-1) != 0 {
+		panic("tree: invalid chunk size")
+	}
+}
+
+// Sum64 computes the tree hash of b with the default chunk size, hashing
+// chunks in parallel.
+func Sum64(b []byte) uint64 {
+	return Sum64WithChunkSize(b, DefaultChunkSize)
+}
+
+// Sum64WithChunkSize computes the tree hash of b with the given chunk size,
+// hashing chunks in parallel. It panics if chunkSize is not a power of two
+// between MinChunkSize and MaxChunkSize.
+func Sum64WithChunkSize(b []byte, chunkSize int) uint64 {
+	checkChunkSize(chunkSize)
+	d := Digest{chunkSize: chunkSize}
+	d.Write(b)
+	return d.Sum64()
+}
+
+// Digest computes a tree hash incrementally. It implements hash.Hash64.
+//
+// Data written to a Digest is hashed as it arrives, so a Digest never holds
+// more than a few words of it. Writes that contain several whole chunks
+// hash those chunks in parallel.
+//
+// The zero value of Digest is ready to use and is equivalent to the result
+// of New.
+type Digest struct {
+	chunkSize int // 0 means DefaultChunkSize
+
+	chunk xxhash.Digest // the current, incomplete chunk
+	n     int           // how much of the current chunk has been written
+	total uint64
+
+	root xxhash.Digest // the leaf digests of the completed chunks
+}
+
+// New creates a new Digest with the default chunk size.
+func New() *Digest {
+	return &Digest{}
+}
+
+// NewWithChunkSize creates a new Digest with the given chunk size. It panics
+// if chunkSize is not a power of two between MinChunkSize and MaxChunkSize.
+func NewWithChunkSize(chunkSize int) *Digest {
+	checkChunkSize(chunkSize)
+	return &Digest{chunkSize: chunkSize}
+}
+
+// ChunkSize returns the chunk size of d.
+func (d *Digest) ChunkSize() int {
+	if d.chunkSize == 0 {
+		return DefaultChunkSize
+	}
+	return d.chunkSize
+}
+
+// Reset clears the Digest's state so that it can be reused.
+// It keeps the chunk size.
+func (d *Digest) Reset() {
+	*d = Digest{chunkSize: d.chunkSize}
+}
+
+// Size always returns 8 bytes.
+func (d *Digest) Size() int { return 8 }
+
+// BlockSize returns the chunk size. Writes are most efficient when they
+// contain whole chunks.
+func (d *Digest) BlockSize() int { return d.ChunkSize() }
+
+// Write adds more data to d. It always returns len(b), nil.
+func (d *Digest) Write(b []byte) (n int, err error) {
+	n = len(b)
+	d.total += uint64(n)
+	c := d.ChunkSize()
+	if d.n > 0 {
+		// Complete the current chunk first.
+		k := c - d.n
+		if k > len(b) {
+			k = len(b)
+		}
+		d.chunk.Write(b[:k])
+		d.n += k
+		b = b[k:]
+		if d.n < c {
+			return
+		}
+		d.root.WriteUint64(d.chunk.Sum64())
+		d.chunk.Reset()
+		d.n = 0
+	}
+	if full := len(b) / c * c; full > 0 {
+		d.writeChunks(b[:full])
+		b = b[full:]
+	}
+	if len(b) > 0 {
+		d.chunk.Write(b)
+		d.n = len(b)
+	}
+	return
+}
+
+// writeChunks adds the leaf digests of the whole chunks in b to d.root.
+func (d *Digest) writeChunks(b []byte) {
+	c := d.ChunkSize()
+	for len(b) > 0 {
+		m := len(b) / c
+		if m > maxBatch {
+			m = maxBatch
+		}
+		if workers := numWorkers(m, m*c); workers > 1 {
+			for _, h := range sumChunks(b[:m*c], c, workers) {
+				d.root.WriteUint64(h)
+			}
+		} else {
+			for i := 0; i < m; i++ {
+				d.root.WriteUint64(xxhash.Sum64(b[i*c : (i+1)*c]))
+			}
+		}
+		b = b[m*c:]
+	}
+}
+
+// Sum appends the current hash to b and returns the resulting slice.
+func (d *Digest) Sum(b []byte) []byte {
+	s := d.Sum64()
+	return append(
+		b,
+		byte(s>>56),
+		byte(s>>48),
+		byte(s>>40),
+		byte(s>>32),
+		byte(s>>24),
+		byte(s>>16),
+		byte(s>>8),
+		byte(s),
+	)
+}
+
+// Sum64 returns the current hash.
+func (d *Digest) Sum64() uint64 {
+	root := d.root
+	if d.n > 0 {
+		root.WriteUint64(d.chunk.Sum64())
+	}
+	return finish(&root, d.total, d.ChunkSize())
+}
+
+// finish appends the trailer to a root message that holds all of the leaf
+// digests and returns the root digest.
+func finish(root *xxhash.Digest, total uint64, chunkSize int) uint64 {
+	root.WriteUint64(total)
+	root.WriteUint64(uint64(chunkSize))
+	root.WriteString(magic)
+	return root.Sum64()
+}
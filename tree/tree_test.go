@@ -0,0 +1,252 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// specInput returns the input used by the test vectors in SPEC.md: byte i is
+// i mod 251.
+func specInput(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+// reference computes the tree hash exactly as SPEC.md describes it.
+func reference(b []byte, chunkSize int) uint64 {
+	var msg []byte
+	for p := b; len(p) > 0; {
+		n := chunkSize
+		if n > len(p) {
+			n = len(p)
+		}
+		msg = appendUint64(msg, xxhash.Sum64(p[:n]))
+		p = p[n:]
+	}
+	msg = appendUint64(msg, uint64(len(b)))
+	msg = appendUint64(msg, uint64(chunkSize))
+	msg = append(msg, "xxhtree"...)
+	msg = append(msg, Version)
+	return xxhash.Sum64(msg)
+}
+
+func appendUint64(b []byte, x uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], x)
+	return append(b, buf[:]...)
+}
+
+// withProcs runs f with GOMAXPROCS set to n, so that the parallel code paths
+// are exercised even on small machines.
+func withProcs(n int, f func()) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(n))
+	f()
+}
+
+func TestVectors(t *testing.T) {
+	// These are the test vectors listed in SPEC.md.
+	for _, tt := range []struct {
+		n         int
+		chunkSize int
+		want      uint64
+	}{
+		{0, 1024, 0x87d8dae867f08c5d},
+		{1, 1024, 0x3ead9e1484319cff},
+		{1023, 1024, 0x08c0bb991706641b},
+		{1024, 1024, 0xddc41546c6004498},
+		{1025, 1024, 0xcfb51ebd23097238},
+		{5000, 1024, 0x84aae161c7202916},
+		{0, 1 << 20, 0xdd670961d3385332},
+		{3<<20 + 17, 1 << 20, 0xc37a38f58bda1161},
+	} {
+		b := specInput(tt.n)
+		if got := reference(b, tt.chunkSize); got != tt.want {
+			t.Errorf("reference(%d bytes, %d): got %#016x; want %#016x", tt.n, tt.chunkSize, got, tt.want)
+		}
+		if got := Sum64WithChunkSize(b, tt.chunkSize); got != tt.want {
+			t.Errorf("Sum64WithChunkSize(%d bytes, %d): got %#016x; want %#016x", tt.n, tt.chunkSize, got, tt.want)
+		}
+	}
+}
+
+var testLengths = []int{0, 1, 31, 32, 1023, 1024, 1025, 3*1024 + 5, 64 << 10, 100<<10 + 3, 1<<20 + 1}
+
+func TestSum64(t *testing.T) {
+	withProcs(4, func() {
+		for _, c := range []int{MinChunkSize, 4 << 10, 64 << 10} {
+			for _, n := range testLengths {
+				b := specInput(n)
+				if got, want := Sum64WithChunkSize(b, c), reference(b, c); got != want {
+					t.Errorf("Sum64WithChunkSize(%d bytes, %d): got %#x; want %#x", n, c, got, want)
+				}
+			}
+		}
+		b := specInput(5<<20 + 1)
+		if got, want := Sum64(b), reference(b, DefaultChunkSize); got != want {
+			t.Errorf("Sum64(%d bytes): got %#x; want %#x", len(b), got, want)
+		}
+	})
+}
+
+func TestDigest(t *testing.T) {
+	withProcs(4, func() {
+		b := specInput(300<<10 + 7)
+		for _, c := range []int{MinChunkSize, 32 << 10} {
+			want := reference(b, c)
+			for _, size := range []int{1, 7, 1000, c, c + 1, 5000, 3 * c, len(b)} {
+				d := NewWithChunkSize(c)
+				for p := b; len(p) > 0; {
+					n := size
+					if n > len(p) {
+						n = len(p)
+					}
+					d.Write(p[:n])
+					p = p[n:]
+				}
+				if got := d.Sum64(); got != want {
+					t.Errorf("chunk size %d, writes of %d: got %#x; want %#x", c, size, got, want)
+				}
+				// Sum64 must not change the state.
+				d.Write(nil)
+				if got := d.Sum64(); got != want {
+					t.Errorf("chunk size %d, writes of %d: second Sum64 gave %#x; want %#x", c, size, got, want)
+				}
+			}
+		}
+	})
+}
+
+func TestDigestZero(t *testing.T) {
+	b := specInput(1<<20 + 100)
+	var d Digest
+	if got, want := d.ChunkSize(), DefaultChunkSize; got != want {
+		t.Fatalf("zero Digest: ChunkSize() = %d; want %d", got, want)
+	}
+	d.Write(b)
+	if got, want := d.Sum64(), Sum64(b); got != want {
+		t.Fatalf("zero Digest: got %#x; want %#x", got, want)
+	}
+	if got, want := New().Sum64(), Sum64(nil); got != want {
+		t.Fatalf("New().Sum64() = %#x; want %#x", got, want)
+	}
+}
+
+func TestDigestReset(t *testing.T) {
+	d := NewWithChunkSize(4096)
+	d.Write(specInput(10000))
+	d.Reset()
+	if got, want := d.ChunkSize(), 4096; got != want {
+		t.Fatalf("after Reset, ChunkSize() = %d; want %d", got, want)
+	}
+	b := specInput(5000)
+	d.Write(b)
+	if got, want := d.Sum64(), reference(b, 4096); got != want {
+		t.Fatalf("after Reset: got %#x; want %#x", got, want)
+	}
+}
+
+func TestDigestSum(t *testing.T) {
+	b := specInput(5000)
+	d := NewWithChunkSize(1024)
+	d.Write(b)
+	got := d.Sum([]byte("x"))
+	want := make([]byte, 9)
+	want[0] = 'x'
+	binary.BigEndian.PutUint64(want[1:], reference(b, 1024))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Sum: got %x; want %x", got, want)
+	}
+	if d.Size() != 8 || d.BlockSize() != 1024 {
+		t.Fatalf("Size, BlockSize = %d, %d; want 8, 1024", d.Size(), d.BlockSize())
+	}
+}
+
+func TestInvalidChunkSize(t *testing.T) {
+	huge := MaxChunkSize
+	huge <<= 1 // not a constant, since it overflows int on 32-bit platforms
+	for _, c := range []int{-1024, 0, 1, 512, 1000, 3 << 10, MaxChunkSize - 1, huge} {
+		t.Run(fmt.Sprint(c), func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("NewWithChunkSize(%d) did not panic", c)
+				}
+			}()
+			NewWithChunkSize(c)
+		})
+	}
+}
+
+func TestSumReaderAt(t *testing.T) {
+	withProcs(4, func() {
+		for _, c := range []int{MinChunkSize, 64 << 10} {
+			for _, n := range testLengths {
+				b := specInput(n)
+				got, err := SumReaderAtWithChunkSize(bytes.NewReader(b), int64(n), c)
+				if err != nil {
+					t.Fatalf("SumReaderAtWithChunkSize(%d bytes, %d): %v", n, c, err)
+				}
+				if want := reference(b, c); got != want {
+					t.Errorf("SumReaderAtWithChunkSize(%d bytes, %d): got %#x; want %#x", n, c, got, want)
+				}
+			}
+		}
+
+		// Chunks larger than the read buffer are read in pieces, and a
+		// prefix of a longer input can be hashed.
+		b := specInput(5<<20 + 3)
+		got, err := SumReaderAtWithChunkSize(bytes.NewReader(b), 5<<20, 2<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := reference(b[:5<<20], 2<<20); got != want {
+			t.Errorf("SumReaderAtWithChunkSize with 2 MiB chunks: got %#x; want %#x", got, want)
+		}
+		got, err = SumReaderAt(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := Sum64(b); got != want {
+			t.Errorf("SumReaderAt: got %#x; want %#x", got, want)
+		}
+	})
+}
+
+type errReaderAt struct {
+	r   io.ReaderAt
+	off int64 // reads at or past off fail
+}
+
+var errTest = errors.New("test error")
+
+func (r errReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > r.off {
+		return 0, errTest
+	}
+	return r.r.ReadAt(p, off)
+}
+
+func TestSumReaderAtErrors(t *testing.T) {
+	withProcs(4, func() {
+		b := specInput(100 << 10)
+		if _, err := SumReaderAtWithChunkSize(bytes.NewReader(b), int64(len(b))+1, 1024); err != io.ErrUnexpectedEOF {
+			t.Errorf("short input: got error %v; want %v", err, io.ErrUnexpectedEOF)
+		}
+		r := errReaderAt{bytes.NewReader(b), 50 << 10}
+		if _, err := SumReaderAtWithChunkSize(r, int64(len(b)), 1024); err != errTest {
+			t.Errorf("failing reader: got error %v; want %v", err, errTest)
+		}
+		if _, err := SumReaderAt(bytes.NewReader(b), -1); err == nil {
+			t.Error("negative size: got no error")
+		}
+	})
+}
@@ -0,0 +1,321 @@
+package xxh3
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+)
+
+// Uint128 is a 128-bit XXH3 hash value.
+type Uint128 struct {
+	Hi uint64
+	Lo uint64
+}
+
+// Sum128 computes the 128-bit XXH3 digest of b with a zero seed.
+func Sum128(b []byte) Uint128 {
+	return Sum128WithSeed(b, 0)
+}
+
+// Sum128WithSeed computes the 128-bit XXH3 digest of b using the given seed.
+func Sum128WithSeed(b []byte, seed uint64) Uint128 {
+	n := len(b)
+	switch {
+	case n <= 16:
+		return hash128Len0To16(b, seed)
+	case n <= 128:
+		return hash128Len17To128(b, seed)
+	case n <= midSizeMax:
+		return hash128Len129To240(b, seed)
+	}
+	if seed == 0 {
+		return hash128Long(b, &defaultSecret)
+	}
+	var secret [secretSize]byte
+	initSecret(&secret, seed)
+	return hash128Long(b, &secret)
+}
+
+func hash128Len0To16(b []byte, seed uint64) Uint128 {
+	s := &defaultSecret
+	n := len(b)
+	switch {
+	case n > 8:
+		bitflipl := (u64(s[32:]) ^ u64(s[40:])) - seed
+		bitfliph := (u64(s[48:]) ^ u64(s[56:])) + seed
+		lo := u64(b)
+		hi := u64(b[n-8:])
+		mhi, mlo := bits.Mul64(lo^hi^bitflipl, prime64_1)
+		mlo += uint64(n-1) << 54
+		hi ^= bitfliph
+		mhi += hi + uint64(uint32(hi))*(prime32_2-1)
+		mlo ^= bits.ReverseBytes64(mhi)
+		hhi, hlo := bits.Mul64(mlo, prime64_2)
+		hhi += mhi * prime64_2
+		return Uint128{Hi: avalanche(hhi), Lo: avalanche(hlo)}
+	case n >= 4:
+		seed ^= uint64(bits.ReverseBytes32(uint32(seed))) << 32
+		lo := u32(b)
+		hi := u32(b[n-4:])
+		in64 := uint64(lo) + uint64(hi)<<32
+		bitflip := (u64(s[16:]) ^ u64(s[24:])) + seed
+		mhi, mlo := bits.Mul64(in64^bitflip, prime64_1+uint64(n)<<2)
+		mhi += mlo << 1
+		mlo ^= mhi >> 3
+		mlo ^= mlo >> 35
+		mlo *= primeMx2
+		mlo ^= mlo >> 28
+		return Uint128{Hi: avalanche(mhi), Lo: mlo}
+	case n > 0:
+		c1 := uint32(b[0])
+		c2 := uint32(b[n>>1])
+		c3 := uint32(b[n-1])
+		combinedl := c1<<16 | c2<<24 | c3 | uint32(n)<<8
+		combinedh := bits.RotateLeft32(bits.ReverseBytes32(combinedl), 13)
+		bitflipl := uint64(u32(s[0:])^u32(s[4:])) + seed
+		bitfliph := uint64(u32(s[8:])^u32(s[12:])) - seed
+		return Uint128{
+			Hi: xxh64Avalanche(uint64(combinedh) ^ bitfliph),
+			Lo: xxh64Avalanche(uint64(combinedl) ^ bitflipl),
+		}
+	default:
+		return Uint128{
+			Hi: xxh64Avalanche(seed ^ u64(s[80:]) ^ u64(s[88:])),
+			Lo: xxh64Avalanche(seed ^ u64(s[64:]) ^ u64(s[72:])),
+		}
+	}
+}
+
+func hash128Len17To128(b []byte, seed uint64) Uint128 {
+	s := &defaultSecret
+	n := len(b)
+	acc := Uint128{Lo: uint64(n) * prime64_1}
+	if n > 32 {
+		if n > 64 {
+			if n > 96 {
+				acc = mix32(acc, b[48:], b[n-64:], s[96:], seed)
+			}
+			acc = mix32(acc, b[32:], b[n-48:], s[64:], seed)
+		}
+		acc = mix32(acc, b[16:], b[n-32:], s[32:], seed)
+	}
+	acc = mix32(acc, b, b[n-16:], s[0:], seed)
+	return finish128(acc, uint64(n), seed)
+}
+
+func hash128Len129To240(b []byte, seed uint64) Uint128 {
+	s := &defaultSecret
+	n := len(b)
+	acc := Uint128{Lo: uint64(n) * prime64_1}
+	for i := 0; i < 4; i++ {
+		acc = mix32(acc, b[32*i:], b[32*i+16:], s[32*i:], seed)
+	}
+	acc.Lo = avalanche(acc.Lo)
+	acc.Hi = avalanche(acc.Hi)
+	for i := 4; i < n/32; i++ {
+		acc = mix32(acc, b[32*i:], b[32*i+16:], s[32*(i-4)+midSizeStartOffset:], seed)
+	}
+	acc = mix32(acc, b[n-16:], b[n-32:], s[secretSizeMin-midSizeLastOffset-16:], -seed)
+	return finish128(acc, uint64(n), seed)
+}
+
+func finish128(acc Uint128, n, seed uint64) Uint128 {
+	lo := acc.Lo + acc.Hi
+	hi := acc.Lo*prime64_1 + acc.Hi*prime64_4 + (n-seed)*prime64_2
+	return Uint128{Hi: -avalanche(hi), Lo: avalanche(lo)}
+}
+
+func mix32(acc Uint128, b1, b2, secret []byte, seed uint64) Uint128 {
+	acc.Lo += mix16(b1, secret, seed)
+	acc.Lo ^= u64(b2) + u64(b2[8:])
+	acc.Hi += mix16(b2, secret[16:], seed)
+	acc.Hi ^= u64(b1) + u64(b1[8:])
+	return acc
+}
+
+func hash128Long(b []byte, secret *[secretSize]byte) Uint128 {
+	acc := initAcc
+	hashLongLoop(&acc, b, secret)
+	return merge128(&acc, secret, uint64(len(b)))
+}
+
+func merge128(acc *[accNb]uint64, secret *[secretSize]byte, n uint64) Uint128 {
+	return Uint128{
+		Hi: mergeAccs(acc, secret[secretSize-stripeLen-secretMergeAccs:], ^(n * prime64_2)),
+		Lo: mergeAccs(acc, secret[secretMergeAccs:], n*prime64_1),
+	}
+}
+
+// Sum128 returns the current 128-bit hash.
+func (d *Digest) Sum128() Uint128 {
+	if d.total <= midSizeMax {
+		return Sum128WithSeed(d.buf[:d.n], d.seed)
+	}
+	acc := d.acc
+	d.digestLong(&acc)
+	return merge128(&acc, &d.secret, d.total)
+}
+
+// Digest128 implements hash.Hash for 128-bit XXH3. Its Sum method appends
+// the canonical (big-endian) form of the 128-bit hash.
+//
+// Note that a zero-valued Digest128 is not ready to receive writes.
+// Call Reset or create a Digest128 using New128 before calling other methods.
+type Digest128 struct {
+	d Digest
+}
+
+// New128 creates a new Digest128 with a zero seed.
+func New128() *Digest128 {
+	return New128WithSeed(0)
+}
+
+// New128WithSeed creates a new Digest128 with the given seed.
+func New128WithSeed(seed uint64) *Digest128 {
+	var d Digest128
+	d.d.reset(seed)
+	return &d
+}
+
+// Reset clears the Digest128's state so that it can be reused.
+// It uses a seed value of zero.
+func (d *Digest128) Reset() {
+	d.d.reset(0)
+}
+
+// ResetWithSeed clears the Digest128's state so that it can be reused.
+// It uses the given seed to initialize the state.
+func (d *Digest128) ResetWithSeed(seed uint64) {
+	d.d.reset(seed)
+}
+
+// Size always returns 16 bytes.
+func (d *Digest128) Size() int { return 16 }
+
+// BlockSize always returns 64 bytes.
+func (d *Digest128) BlockSize() int { return stripeLen }
+
+// Write adds more data to d. It always returns len(b), nil.
+func (d *Digest128) Write(b []byte) (n int, err error) {
+	return d.d.Write(b)
+}
+
+// WriteString adds more data to d. It always returns len(s), nil.
+func (d *Digest128) WriteString(s string) (n int, err error) {
+	return d.d.WriteString(s)
+}
+
+// Sum appends the canonical form of the current hash to b and returns the
+// resulting slice.
+func (d *Digest128) Sum(b []byte) []byte {
+	b, _ = d.d.Sum128().AppendBinary(b)
+	return b
+}
+
+// Sum128 returns the current hash.
+func (d *Digest128) Sum128() Uint128 {
+	return d.d.Sum128()
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+// The state format is shared with Digest.
+func (d *Digest128) MarshalBinary() ([]byte, error) {
+	return d.d.MarshalBinary()
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (d *Digest128) UnmarshalBinary(b []byte) error {
+	return d.d.UnmarshalBinary(b)
+}
+
+// Bytes returns the canonical representation of u: Hi followed by Lo, each
+// in big-endian byte order. This matches XXH128_canonicalFromHash in the
+// reference implementation.
+func (u Uint128) Bytes() [16]byte {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[0:8], u.Hi)
+	binary.BigEndian.PutUint64(b[8:16], u.Lo)
+	return b
+}
+
+// AppendBinary appends the canonical representation of u (see Bytes) to b
+// and returns the resulting slice. It never returns an error.
+func (u Uint128) AppendBinary(b []byte) ([]byte, error) {
+	c := u.Bytes()
+	return append(b, c[:]...), nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface using
+// the canonical representation of u.
+func (u Uint128) MarshalBinary() ([]byte, error) {
+	return u.AppendBinary(make([]byte, 0, 16))
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// It accepts the canonical representation produced by MarshalBinary.
+func (u *Uint128) UnmarshalBinary(b []byte) error {
+	if len(b) != 16 {
+		return errors.New("xxh3: invalid Uint128 length")
+	}
+	u.Hi = binary.BigEndian.Uint64(b[0:8])
+	u.Lo = binary.BigEndian.Uint64(b[8:16])
+	return nil
+}
+
+// String returns u as 32 lowercase hexadecimal digits, the same form that
+// the reference xxhsum tool prints for 128-bit hashes (xxhsum -H2).
+func (u Uint128) String() string {
+	c := u.Bytes()
+	return hex.EncodeToString(c[:])
+}
+
+// MarshalText implements the encoding.TextMarshaler interface using the
+// format described in String.
+func (u Uint128) MarshalText() ([]byte, error) {
+	c := u.Bytes()
+	b := make([]byte, hex.EncodedLen(len(c)))
+	hex.Encode(b, c[:])
+	return b, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// It accepts exactly 32 hexadecimal digits, in either case.
+func (u *Uint128) UnmarshalText(text []byte) error {
+	if len(text) != 32 {
+		return errors.New("xxh3: invalid Uint128 text length")
+	}
+	var c [16]byte
+	if _, err := hex.Decode(c[:], text); err != nil {
+		return errors.New("xxh3: invalid Uint128 text: " + err.Error())
+	}
+	return u.UnmarshalBinary(c[:])
+}
+
+// Equal reports whether u and v are the same value.
+func (u Uint128) Equal(v Uint128) bool {
+	return u == v
+}
+
+// Cmp compares u and v as unsigned 128-bit integers and returns -1, 0,
+// or +1 depending on whether u is less than, equal to, or greater than v.
+// This matches XXH128_cmp in the reference implementation.
+func (u Uint128) Cmp(v Uint128) int {
+	switch {
+	case u.Hi < v.Hi:
+		return -1
+	case u.Hi > v.Hi:
+		return 1
+	case u.Lo < v.Lo:
+		return -1
+	case u.Lo > v.Lo:
+		return 1
+	}
+	return 0
+}
+
+// Less reports whether u < v when both are treated as unsigned 128-bit
+// integers.
+func (u Uint128) Less(v Uint128) bool {
+	return u.Cmp(v) < 0
+}
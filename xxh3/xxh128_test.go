@@ -0,0 +1,234 @@
+package xxh3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+var testVectors128 = []struct {
+	n    int
+	seed uint64
+	want Uint128
+}{
+	{0, 0, Uint128{0x99aa06d3014798d8, 0x6001c324468d497f}},
+	{1, 0, Uint128{0xa6cd5e9392000f6a, 0xc44bdff4074eecdb}},
+	{2, 0, Uint128{0x76750c3c7bf95668, 0x7a9978044cb8a8bb}},
+	{3, 0, Uint128{0x20efc49ff02422ea, 0x54247382a8d6b94d}},
+	{4, 0, Uint128{0x970d585ac632bf8e, 0x2e7d8d6876a39fe9}},
+	{6, 0, Uint128{0x082afe0b8162d12a, 0x3e7039bdda43cfc6}},
+	{8, 0, Uint128{0x47a7f080d82bb456, 0x64c69cab4bb21dc5}},
+	{9, 0, Uint128{0x564ef6078950d457, 0xed7ccbc501eb7501}},
+	{12, 0, Uint128{0x6e3efd8fc7802b18, 0x061a192713f69ad9}},
+	{16, 0, Uint128{0xc68c368ecf8a9c05, 0x562980258a998629}},
+	{17, 0, Uint128{0x955fa78643ed3669, 0xabbc12d11973d7db}},
+	{24, 0, Uint128{0x0ce966e4678d3761, 0x1e7044d28b1b901d}},
+	{32, 0, Uint128{0x98fc6458710dc2e8, 0x278410a17595e3f9}},
+	{33, 0, Uint128{0x3103c192ceaa2ded, 0xe593bc4e5914c9d1}},
+	{48, 0, Uint128{0xa002ac4e5478227e, 0xf942219aed80f67b}},
+	{64, 0, Uint128{0x6d90e81a9b0fd622, 0xefdb6a44690721a9}},
+	{65, 0, Uint128{0x6c074d65e54db85a, 0xfe2f650fa500ec6e}},
+	{80, 0, Uint128{0xfdf2cefde9eaac8a, 0x454ae6bf7a8a532d}},
+	{96, 0, Uint128{0xd9d0b885f56c93f1, 0xe9324473ea9afebe}},
+	{97, 0, Uint128{0x09dff37faa6b284c, 0x7c87228ae9671ba7}},
+	{112, 0, Uint128{0xd1b2cf3831d419ad, 0xcbfd9024e4b6c79d}},
+	{128, 0, Uint128{0x39992220e045260a, 0xebb15e34a7fb5ab1}},
+	{129, 0, Uint128{0x03815fc91f1b30b6, 0x86c9e3bc8f0a3b5c}},
+	{195, 0, Uint128{0x7729543a26b207ee, 0x3fb593c086a66075}},
+	{240, 0, Uint128{0xaa4202daa2769dc8, 0x5c9aae94c8ebe5a0}},
+	{241, 0, Uint128{0x99a80ecf0ecfc647, 0xc5a639ecd2030e5e}},
+	{255, 0, Uint128{0x961375c87e09efbc, 0xe98f979f4ed8a197}},
+	{256, 0, Uint128{0x8b1c66091423d288, 0x55de574ad89d0ac5}},
+	{257, 0, Uint128{0xf15fee7f9f457599, 0xb17fd5a8ae75bb0b}},
+	{403, 0, Uint128{0x1b6de21e332dd73d, 0xcdeb804d65c6dea4}},
+	{512, 0, Uint128{0x18d2d110dcc9bca1, 0x617e49599013cb6b}},
+	{1024, 0, Uint128{0x0d30d24071c64c57, 0xdd85c9b5c1109c5c}},
+	{1025, 0, Uint128{0xfd3ee4fe7f2954c6, 0xd870c0fa13211c6a}},
+	{2048, 0, Uint128{0xf736557fd47073a5, 0xdd59e2c3a5f038e0}},
+	{2240, 0, Uint128{0xccb134fbfa7ce49d, 0x6e73a90539cf2948}},
+	{2367, 0, Uint128{0xe89c0f6ff369b427, 0xcb37aeb9e5d361ed}},
+	{4096, 0, Uint128{0xb9cfaea2ca5626a4, 0xe91206429d1f48f9}},
+
+	{0, sanitySeed, Uint128{0x00feaa732a3ce25e, 0xa986dfc5d7605bfe}},
+	{1, sanitySeed, Uint128{0x20e49abcc53b3842, 0x032be332dd766ef8}},
+	{2, sanitySeed, Uint128{0x7b96e6a600dae67d, 0x764b35c90519ad88}},
+	{3, sanitySeed, Uint128{0x1c7ecf6a308cf00e, 0x634b8990b4976373}},
+	{4, sanitySeed, Uint128{0x3d53e5dfd837d927, 0xbfaf51f1e67e0b0f}},
+	{6, sanitySeed, Uint128{0x014bd95a51ca5ddb, 0xc5b54d56038e4e40}},
+	{8, sanitySeed, Uint128{0xf50cec145bcd5c5a, 0x7b29471dc729b5ff}},
+	{9, sanitySeed, Uint128{0x6b380b43ffa61042, 0xaef5dfc0ac9f9044}},
+	{12, sanitySeed, Uint128{0xff0d60acd02ed401, 0x5d92b5d7190b12d1}},
+	{16, sanitySeed, Uint128{0x6ffcb80cd33085c8, 0x0346d13a7a5498c7}},
+	{17, sanitySeed, Uint128{0xd77681219e464828, 0x980a14119985a7df}},
+	{24, sanitySeed, Uint128{0xd7895ded1f62559d, 0xc6cbf92a70680b19}},
+	{32, sanitySeed, Uint128{0xcc587e4fcdb86bc5, 0x0054e82631cef166}},
+	{33, sanitySeed, Uint128{0x21273c8190c645cd, 0xc361d36cea597c31}},
+	{48, sanitySeed, Uint128{0xbc689f4c0152fb44, 0x3a94d91333ed395a}},
+	{64, sanitySeed, Uint128{0x37b738968d40bda5, 0x9405ba2affa95ceb}},
+	{65, sanitySeed, Uint128{0x72503a6fa8d07adb, 0x9d60c345e5c297cd}},
+	{80, sanitySeed, Uint128{0x19bf02d69bc56833, 0xa5eac764d1ff1166}},
+	{96, sanitySeed, Uint128{0x6f9ed3c2008cb388, 0xd61f3ab58705c405}},
+	{97, sanitySeed, Uint128{0x14e68f850b481ada, 0x49ea87f2afe44f66}},
+	{112, sanitySeed, Uint128{0x431161e8efc337f6, 0x94de4eb7f80e7448}},
+	{128, sanitySeed, Uint128{0xa0f7ccb68ee02add, 0x8394f5c51f1d8246}},
+	{129, sanitySeed, Uint128{0xad559266067c0bf3, 0xd4aae26fcec7dc03}},
+	{195, sanitySeed, Uint128{0x0326104c4d4849e7, 0xcf9d9ec2c8c9913f}},
+	{240, sanitySeed, Uint128{0x29d2133d6ea58c5b, 0x604e98db085c1864}},
+	{241, sanitySeed, Uint128{0xec64afae6a137582, 0xdda9b0a161d4829a}},
+	{255, sanitySeed, Uint128{0xe72ec0137d62df44, 0x2aca7901d9538c75}},
+	{256, sanitySeed, Uint128{0xaaa57235b92d5e7c, 0x4d30234b7a3aa61c}},
+	{257, sanitySeed, Uint128{0x15c1f9c667c815ba, 0x802a6fbf3cacd97c}},
+	{403, sanitySeed, Uint128{0xbed311971e0be8f2, 0x6259f6ecfd6443fd}},
+	{512, sanitySeed, Uint128{0x925d06b8ec5b8040, 0x3ce457de14c27708}},
+	{1024, sanitySeed, Uint128{0x17600efe2b493a18, 0xef368a8a2ebabaef}},
+	{1025, sanitySeed, Uint128{0x2c383949f57bf7e1, 0x96792bcf9af88519}},
+	{2048, sanitySeed, Uint128{0x23cc3a2e75ebaaea, 0x66f81670669ababc}},
+	{2240, sanitySeed, Uint128{0xe40842f585875ba9, 0x757ba8487d1b5247}},
+	{2367, sanitySeed, Uint128{0xccb7a94cca1a6496, 0xd2db3415b942b42a}},
+	{4096, sanitySeed, Uint128{0x8fbc8fd4d526d1bd, 0x2a3bbb20a5439dcd}},
+}
+
+func TestAll128(t *testing.T) {
+	for _, tt := range testVectors128 {
+		input := sanityBuffer[:tt.n]
+		name := fmt.Sprintf("len=%d", tt.n)
+		if tt.seed != 0 {
+			name += fmt.Sprintf(",seed=%d", tt.seed)
+		}
+		t.Run(name, func(t *testing.T) {
+			testSum128(t, input, tt.seed, tt.want)
+			for _, chunkSize := range chunkSizes {
+				testDigest128(t, input, tt.seed, chunkSize, tt.want)
+			}
+		})
+	}
+}
+
+func testSum128(t *testing.T, input []byte, seed uint64, want Uint128) {
+	t.Helper()
+	if got := Sum128WithSeed(input, seed); got != want {
+		t.Fatalf("Sum128WithSeed: got %s; want %s", got, want)
+	}
+	if got := Sum128StringWithSeed(string(input), seed); got != want {
+		t.Fatalf("Sum128StringWithSeed: got %s; want %s", got, want)
+	}
+	if seed != 0 {
+		return
+	}
+	if got := Sum128(input); got != want {
+		t.Fatalf("Sum128: got %s; want %s", got, want)
+	}
+	if got := Sum128String(string(input)); got != want {
+		t.Fatalf("Sum128String: got %s; want %s", got, want)
+	}
+}
+
+func testDigest128(t *testing.T, input []byte, seed uint64, chunkSize int, want Uint128) {
+	t.Helper()
+	d := New128WithSeed(seed)
+	d64 := NewWithSeed(seed)
+	for i := 0; i < len(input); i += chunkSize {
+		chunk := input[i:]
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		n, err := d.Write(chunk)
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Digest128.Write: got (%d, %v); want (%d, nil)", n, err, len(chunk))
+		}
+		d64.WriteString(string(chunk))
+	}
+	if got := d.Sum128(); got != want {
+		t.Fatalf("chunkSize=%d: Digest128.Sum128: got %s; want %s", chunkSize, got, want)
+	}
+	if got := d64.Sum128(); got != want {
+		t.Fatalf("chunkSize=%d: Digest.Sum128: got %s; want %s", chunkSize, got, want)
+	}
+	c := want.Bytes()
+	if got := d.Sum(nil); !bytes.Equal(got, c[:]) {
+		t.Fatalf("chunkSize=%d: Sum: got %x; want %x", chunkSize, got, c[:])
+	}
+}
+
+func TestUint128Encoding(t *testing.T) {
+	// The expected text is what xxhsum -H2 prints for empty input.
+	u := Sum128(nil)
+	const want = "99aa06d3014798d86001c324468d497f"
+	if got := u.String(); got != want {
+		t.Fatalf("String: got %q; want %q", got, want)
+	}
+	text, err := u.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != want {
+		t.Fatalf("MarshalText: got %q; want %q", text, want)
+	}
+	var u1 Uint128
+	if err := u1.UnmarshalText([]byte("99AA06D3014798D86001C324468D497F")); err != nil {
+		t.Fatal(err)
+	}
+	if u1 != u {
+		t.Fatalf("UnmarshalText: got %s; want %s", u1, u)
+	}
+
+	b, err := u.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%x", b); got != want {
+		t.Fatalf("MarshalBinary: got %s; want %s", got, want)
+	}
+	b, _ = u.AppendBinary([]byte("x"))
+	if got := fmt.Sprintf("%x", b); got != "78"+want {
+		t.Fatalf("AppendBinary: got %s; want 78%s", got, want)
+	}
+	var u2 Uint128
+	if err := u2.UnmarshalBinary(b[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if u2 != u {
+		t.Fatalf("UnmarshalBinary: got %s; want %s", u2, u)
+	}
+
+	js, err := json.Marshal(map[string]Uint128{"h": u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(js), `{"h":"`+want+`"}`; got != want {
+		t.Fatalf("json.Marshal: got %s; want %s", got, want)
+	}
+
+	for _, bad := range []string{"", "99aa", want + "00", "zzaa06d3014798d86001c324468d497f"} {
+		if err := u1.UnmarshalText([]byte(bad)); err == nil {
+			t.Errorf("UnmarshalText(%q): got nil error", bad)
+		}
+	}
+	if err := u1.UnmarshalBinary(b); err == nil {
+		t.Error("UnmarshalBinary accepted 17 bytes")
+	}
+}
+
+func TestUint128Cmp(t *testing.T) {
+	for _, tt := range []struct {
+		u, v Uint128
+		want int
+	}{
+		{Uint128{0, 0}, Uint128{0, 0}, 0},
+		{Uint128{0, 1}, Uint128{0, 2}, -1},
+		{Uint128{1, 0}, Uint128{0, ^uint64(0)}, 1},
+		{Uint128{^uint64(0), 5}, Uint128{^uint64(0), 5}, 0},
+		{Uint128{2, 0}, Uint128{3, 0}, -1},
+	} {
+		if got := tt.u.Cmp(tt.v); got != tt.want {
+			t.Errorf("%s.Cmp(%s): got %d; want %d", tt.u, tt.v, got, tt.want)
+		}
+		if got, want := tt.u.Less(tt.v), tt.want < 0; got != want {
+			t.Errorf("%s.Less(%s): got %t; want %t", tt.u, tt.v, got, want)
+		}
+		if got, want := tt.u.Equal(tt.v), tt.want == 0; got != want {
+			t.Errorf("%s.Equal(%s): got %t; want %t", tt.u, tt.v, got, want)
+		}
+	}
+}
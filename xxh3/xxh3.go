@@ -0,0 +1,502 @@
+// Package xxh3 implements the 64-bit and 128-bit variants of the XXH3 hash
+// algorithm (XXH3_64bits and XXH128) as described at https://xxhash.com/.
+//
+// XXH3 is a different algorithm from XXH64 (implemented by the parent xxhash
+// package) and produces different hash values. It is considerably faster on
+// small inputs.
+package xxh3
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+const (
+	prime32_1 uint64 = 0x9E3779B1
+	prime32_2 uint64 = 0x85EBCA77
+	prime32_3 uint64 = 0xC2B2AE3D
+
+	prime64_1 uint64 = 0x9E3779B185EBCA87
+	prime64_2 uint64 = 0xC2B2AE3D27D4EB4F
+	prime64_3 uint64 = 0x165667B19E3779F9
+	prime64_4 uint64 = 0x85EBCA77C2B2AE63
+	prime64_5 uint64 = 0x27D4EB2F165667C5
+
+	primeMx1 uint64 = 0x165667919E3779F9
+	primeMx2 uint64 = 0x9FB21C651E98DF25
+)
+
+const (
+	secretSize = 192
+
+	stripeLen       = 64
+	secretConsume   = 8
+	accNb           = stripeLen / 8
+	stripesPerBlock = (secretSize - stripeLen) / secretConsume
+	blockLen        = stripeLen * stripesPerBlock
+
+	midSizeMax         = 240
+	midSizeStartOffset = 3
+	midSizeLastOffset  = 17
+	secretSizeMin      = 136
+	secretLastAcc      = 7
+	secretMergeAccs    = 11
+)
+
+// defaultSecret is the kSecret value from the reference implementation.
+var defaultSecret = [secretSize]byte{
+	0xb8, 0xfe, 0x6c, 0x39, 0x23, 0xa4, 0x4b, 0xbe, 0x7c, 0x01, 0x81, 0x2c, 0xf7, 0x21, 0xad, 0x1c,
+	0xde, 0xd4, 0x6d, 0xe9, 0x83, 0x90, 0x97, 0xdb, 0x72, 0x40, 0xa4, 0xa4, 0xb7, 0xb3, 0x67, 0x1f,
+	0xcb, 0x79, 0xe6, 0x4e, 0xcc, 0xc0, 0xe5, 0x78, 0x82, 0x5a, 0xd0, 0x7d, 0xcc, 0xff, 0x72, 0x21,
+	0xb8, 0x08, 0x46, 0x74, 0xf7, 0x43, 0x24, 0x8e, 0xe0, 0x35, 0x90, 0xe6, 0x81, 0x3a, 0x26, 0x4c,
+	0x3c, 0x28, 0x52, 0xbb, 0x91, 0xc3, 0x00, 0xcb, 0x88, 0xd0, 0x65, 0x8b, 0x1b, 0x53, 0x2e, 0xa3,
+	0x71, 0x64, 0x48, 0x97, 0xa2, 0x0d, 0xf9, 0x4e, 0x38, 0x19, 0xef, 0x46, 0xa9, 0xde, 0xac, 0xd8,
+	0xa8, 0xfa, 0x76, 0x3f, 0xe3, 0x9c, 0x34, 0x3f, 0xf9, 0xdc, 0xbb, 0xc7, 0xc7, 0x0b, 0x4f, 0x1d,
+	0x8a, 0x51, 0xe0, 0x4b, 0xcd, 0xb4, 0x59, 0x31, 0xc8, 0x9f, 0x7e, 0xc9, 0xd9, 0x78, 0x73, 0x64,
+	0xea, 0xc5, 0xac, 0x83, 0x34, 0xd3, 0xeb, 0xc3, 0xc5, 0x81, 0xa0, 0xff, 0xfa, 0x13, 0x63, 0xeb,
+	0x17, 0x0d, 0xdd, 0x51, 0xb7, 0xf0, 0xda, 0x49, 0xd3, 0x16, 0x55, 0x26, 0x29, 0xd4, 0x68, 0x9e,
+	0x2b, 0x16, 0xbe, 0x58, 0x7d, 0x47, 0xa1, 0xfc, 0x8f, 0xf8, 0xb8, 0xd1, 0x7a, 0xd0, 0x31, 0xce,
+	0x45, 0xcb, 0x3a, 0x8f, 0x95, 0x16, 0x04, 0x28, 0xaf, 0xd7, 0xfb, 0xca, 0xbb, 0x4b, 0x40, 0x7e,
+}
+
+// Sum64 computes the 64-bit XXH3 digest of b with a zero seed.
+func Sum64(b []byte) uint64 {
+	return Sum64WithSeed(b, 0)
+}
+
+// Sum64WithSeed computes the 64-bit XXH3 digest of b using the given seed.
+func Sum64WithSeed(b []byte, seed uint64) uint64 {
+	n := len(b)
+	switch {
+	case n <= 16:
+		return hashLen0To16(b, seed)
+	case n <= 128:
+		return hashLen17To128(b, seed)
+	case n <= midSizeMax:
+		return hashLen129To240(b, seed)
+	}
+	if seed == 0 {
+		return hashLong(b, &defaultSecret)
+	}
+	var secret [secretSize]byte
+	initSecret(&secret, seed)
+	return hashLong(b, &secret)
+}
+
+func hashLen0To16(b []byte, seed uint64) uint64 {
+	s := &defaultSecret
+	n := len(b)
+	switch {
+	case n > 8:
+		bitflip1 := (u64(s[24:]) ^ u64(s[32:])) + seed
+		bitflip2 := (u64(s[40:]) ^ u64(s[48:])) - seed
+		lo := u64(b) ^ bitflip1
+		hi := u64(b[n-8:]) ^ bitflip2
+		acc := uint64(n) + bits.ReverseBytes64(lo) + hi + mulFold64(lo, hi)
+		return avalanche(acc)
+	case n >= 4:
+		seed ^= uint64(bits.ReverseBytes32(uint32(seed))) << 32
+		in1 := u32(b)
+		in2 := u32(b[n-4:])
+		bitflip := (u64(s[8:]) ^ u64(s[16:])) - seed
+		in64 := uint64(in2) + uint64(in1)<<32
+		return rrmxmx(in64^bitflip, uint64(n))
+	case n > 0:
+		c1 := uint32(b[0])
+		c2 := uint32(b[n>>1])
+		c3 := uint32(b[n-1])
+		combined := c1<<16 | c2<<24 | c3 | uint32(n)<<8
+		bitflip := uint64(u32(s[0:])^u32(s[4:])) + seed
+		return xxh64Avalanche(uint64(combined) ^ bitflip)
+	default:
+		return xxh64Avalanche(seed ^ u64(s[56:]) ^ u64(s[64:]))
+	}
+}
+
+func hashLen17To128(b []byte, seed uint64) uint64 {
+	s := &defaultSecret
+	n := len(b)
+	acc := uint64(n) * prime64_1
+	if n > 32 {
+		if n > 64 {
+			if n > 96 {
+				acc += mix16(b[48:], s[96:], seed)
+				acc += mix16(b[n-64:], s[112:], seed)
+			}
+			acc += mix16(b[32:], s[64:], seed)
+			acc += mix16(b[n-48:], s[80:], seed)
+		}
+		acc += mix16(b[16:], s[32:], seed)
+		acc += mix16(b[n-32:], s[48:], seed)
+	}
+	acc += mix16(b, s[0:], seed)
+	acc += mix16(b[n-16:], s[16:], seed)
+	return avalanche(acc)
+}
+
+func hashLen129To240(b []byte, seed uint64) uint64 {
+	s := &defaultSecret
+	n := len(b)
+	acc := uint64(n) * prime64_1
+	for i := 0; i < 8; i++ {
+		acc += mix16(b[16*i:], s[16*i:], seed)
+	}
+	acc = avalanche(acc)
+	for i := 8; i < n/16; i++ {
+		acc += mix16(b[16*i:], s[16*(i-8)+midSizeStartOffset:], seed)
+	}
+	acc += mix16(b[n-16:], s[secretSizeMin-midSizeLastOffset:], seed)
+	return avalanche(acc)
+}
+
+func hashLong(b []byte, secret *[secretSize]byte) uint64 {
+	acc := initAcc
+	hashLongLoop(&acc, b, secret)
+	return mergeAccs(&acc, secret[secretMergeAccs:], uint64(len(b))*prime64_1)
+}
+
+var initAcc = [accNb]uint64{
+	prime32_3, prime64_1, prime64_2, prime64_3,
+	prime64_4, prime32_2, prime64_5, prime32_1,
+}
+
+// hashLongLoop processes all of b (which must be longer than one stripe)
+// into acc, including the final, possibly overlapping, stripe.
+func hashLongLoop(acc *[accNb]uint64, b []byte, secret *[secretSize]byte) {
+	n := len(b)
+	nblocks := (n - 1) / blockLen
+	for i := 0; i < nblocks; i++ {
+		accumulate(acc, b[i*blockLen:], secret[:], stripesPerBlock)
+		scramble(acc, secret[secretSize-stripeLen:])
+	}
+	nstripes := ((n - 1) - blockLen*nblocks) / stripeLen
+	accumulate(acc, b[nblocks*blockLen:], secret[:], nstripes)
+	accumulate512(acc, b[n-stripeLen:], secret[secretSize-stripeLen-secretLastAcc:])
+}
+
+// accumulate accumulates nstripes consecutive stripes of b into acc. Each
+// stripe uses the secret shifted by secretConsume bytes relative to the last.
+func accumulate(acc *[accNb]uint64, b, secret []byte, nstripes int) {
+	a0, a1, a2, a3 := acc[0], acc[1], acc[2], acc[3]
+	a4, a5, a6, a7 := acc[4], acc[5], acc[6], acc[7]
+	for i := 0; i < nstripes; i++ {
+		p := b[i*stripeLen : i*stripeLen+stripeLen : len(b)]
+		s := secret[i*secretConsume : i*secretConsume+stripeLen : len(secret)]
+
+		v0, v1 := u64(p[0:8]), u64(p[8:16])
+		k0, k1 := v0^u64(s[0:8]), v1^u64(s[8:16])
+		a0 += v1 + uint64(uint32(k0))*(k0>>32)
+		a1 += v0 + uint64(uint32(k1))*(k1>>32)
+
+		v2, v3 := u64(p[16:24]), u64(p[24:32])
+		k2, k3 := v2^u64(s[16:24]), v3^u64(s[24:32])
+		a2 += v3 + uint64(uint32(k2))*(k2>>32)
+		a3 += v2 + uint64(uint32(k3))*(k3>>32)
+
+		v4, v5 := u64(p[32:40]), u64(p[40:48])
+		k4, k5 := v4^u64(s[32:40]), v5^u64(s[40:48])
+		a4 += v5 + uint64(uint32(k4))*(k4>>32)
+		a5 += v4 + uint64(uint32(k5))*(k5>>32)
+
+		v6, v7 := u64(p[48:56]), u64(p[56:64])
+		k6, k7 := v6^u64(s[48:56]), v7^u64(s[56:64])
+		a6 += v7 + uint64(uint32(k6))*(k6>>32)
+		a7 += v6 + uint64(uint32(k7))*(k7>>32)
+	}
+	acc[0], acc[1], acc[2], acc[3] = a0, a1, a2, a3
+	acc[4], acc[5], acc[6], acc[7] = a4, a5, a6, a7
+}
+
+// accumulate512 accumulates a single stripe of b into acc.
+func accumulate512(acc *[accNb]uint64, b, secret []byte) {
+	accumulate(acc, b, secret, 1)
+}
+
+func scramble(acc *[accNb]uint64, secret []byte) {
+	secret = secret[:stripeLen:len(secret)]
+	for i := 0; i < accNb; i++ {
+		a := acc[i]
+		a ^= a >> 47
+		a ^= u64(secret[8*i:])
+		a *= prime32_1
+		acc[i] = a
+	}
+}
+
+func mergeAccs(acc *[accNb]uint64, secret []byte, start uint64) uint64 {
+	secret = secret[:64:len(secret)]
+	h := start
+	for i := 0; i < 4; i++ {
+		h += mulFold64(acc[2*i]^u64(secret[16*i:]), acc[2*i+1]^u64(secret[16*i+8:]))
+	}
+	return avalanche(h)
+}
+
+// initSecret derives a custom secret from the default secret and seed.
+func initSecret(secret *[secretSize]byte, seed uint64) {
+	for i := 0; i < secretSize; i += 16 {
+		binary.LittleEndian.PutUint64(secret[i:], u64(defaultSecret[i:])+seed)
+		binary.LittleEndian.PutUint64(secret[i+8:], u64(defaultSecret[i+8:])-seed)
+	}
+}
+
+func mix16(b, secret []byte, seed uint64) uint64 {
+	lo := u64(b)
+	hi := u64(b[8:])
+	return mulFold64(lo^(u64(secret)+seed), hi^(u64(secret[8:])-seed))
+}
+
+// mulFold64 computes the 128-bit product of x and y and folds it to 64 bits
+// by XORing the high and low halves.
+func mulFold64(x, y uint64) uint64 {
+	hi, lo := bits.Mul64(x, y)
+	return hi ^ lo
+}
+
+func avalanche(h uint64) uint64 {
+	h ^= h >> 37
+	h *= primeMx1
+	h ^= h >> 32
+	return h
+}
+
+func rrmxmx(h, n uint64) uint64 {
+	h ^= bits.RotateLeft64(h, 49) ^ bits.RotateLeft64(h, 24)
+	h *= primeMx2
+	h ^= (h >> 35) + n
+	h *= primeMx2
+	h ^= h >> 28
+	return h
+}
+
+func xxh64Avalanche(h uint64) uint64 {
+	h ^= h >> 33
+	h *= prime64_2
+	h ^= h >> 29
+	h *= prime64_3
+	h ^= h >> 32
+	return h
+}
+
+func u64(b []byte) uint64 { return binary.LittleEndian.Uint64(b) }
+func u32(b []byte) uint32 { return binary.LittleEndian.Uint32(b) }
+
+const bufSize = 256
+
+// Digest implements hash.Hash64 for XXH3.
+//
+// Note that a zero-valued Digest is not ready to receive writes.
+// Call Reset or create a Digest using New before calling other methods.
+type Digest struct {
+	acc     [accNb]uint64
+	seed    uint64
+	total   uint64
+	secret  [secretSize]byte
+	buf     [bufSize]byte
+	n       int // how much of buf is used
+	stripes int // how many stripes of the current block have been consumed
+}
+
+// New creates a new Digest with a zero seed.
+func New() *Digest {
+	return NewWithSeed(0)
+}
+
+// NewWithSeed creates a new Digest with the given seed.
+func NewWithSeed(seed uint64) *Digest {
+	var d Digest
+	d.ResetWithSeed(seed)
+	return &d
+}
+
+// Reset clears the Digest's state so that it can be reused.
+// It uses a seed value of zero.
+func (d *Digest) Reset() {
+	d.ResetWithSeed(0)
+}
+
+// ResetWithSeed clears the Digest's state so that it can be reused.
+// It uses the given seed to initialize the state.
+func (d *Digest) ResetWithSeed(seed uint64) {
+	d.reset(seed)
+}
+
+// reset is kept out of line so that NewWithSeed is cheap enough to be
+// inlined, which lets callers keep their Digest on the stack.
+//
+//go:noinline
+func (d *Digest) reset(seed uint64) {
+	d.acc = initAcc
+	d.seed = seed
+	initSecret(&d.secret, seed)
+	d.total = 0
+	d.n = 0
+	d.stripes = 0
+}
+
+// Size always returns 8 bytes.
+func (d *Digest) Size() int { return 8 }
+
+// BlockSize always returns 64 bytes.
+func (d *Digest) BlockSize() int { return stripeLen }
+
+// Write adds more data to d. It always returns len(b), nil.
+func (d *Digest) Write(b []byte) (n int, err error) {
+	n = len(b)
+	d.total += uint64(n)
+
+	if d.n+n <= bufSize {
+		// The new data fits in the buffer. Note that the buffer is only
+		// consumed once more data arrives so that the final stripe is
+		// always available to Sum64.
+		copy(d.buf[d.n:], b)
+		d.n += n
+		return
+	}
+
+	if d.n > 0 {
+		// Fill and consume the buffer.
+		c := copy(d.buf[d.n:], b)
+		d.consumeStripes(d.buf[:], bufSize/stripeLen)
+		b = b[c:]
+	}
+
+	// Consume whole buffer-sized chunks directly from b, always leaving
+	// at least one byte behind.
+	if len(b) > bufSize {
+		c := (len(b) - 1) / bufSize * bufSize
+		d.consumeStripes(b[:c], c/stripeLen)
+		// Keep the final stripe around in case fewer than
+		// stripeLen bytes are left over.
+		copy(d.buf[bufSize-stripeLen:], b[c-stripeLen:c])
+		b = b[c:]
+	}
+
+	// Store the remaining (non-empty) partial buffer.
+	copy(d.buf[:], b)
+	d.n = len(b)
+
+	return
+}
+
+// consumeStripes accumulates nstripes stripes of b, scrambling the
+// accumulators each time a block is completed.
+func (d *Digest) consumeStripes(b []byte, nstripes int) {
+	d.stripes = consumeStripes(&d.acc, &d.secret, d.stripes, b, nstripes)
+}
+
+// consumeStripes accumulates nstripes stripes of b into acc given that done
+// stripes of the current block have already been accumulated. It returns the
+// updated number of stripes accumulated in the current block.
+func consumeStripes(acc *[accNb]uint64, secret *[secretSize]byte, done int, b []byte, nstripes int) int {
+	for {
+		k := stripesPerBlock - done
+		if k > nstripes {
+			accumulate(acc, b, secret[done*secretConsume:], nstripes)
+			return done + nstripes
+		}
+		accumulate(acc, b, secret[done*secretConsume:], k)
+		scramble(acc, secret[secretSize-stripeLen:])
+		b = b[k*stripeLen:]
+		nstripes -= k
+		done = 0
+	}
+}
+
+// Sum appends the current hash to b and returns the resulting slice.
+func (d *Digest) Sum(b []byte) []byte {
+	s := d.Sum64()
+	return append(
+		b,
+		byte(s>>56),
+		byte(s>>48),
+		byte(s>>40),
+		byte(s>>32),
+		byte(s>>24),
+		byte(s>>16),
+		byte(s>>8),
+		byte(s),
+	)
+}
+
+// Sum64 returns the current hash.
+func (d *Digest) Sum64() uint64 {
+	if d.total <= midSizeMax {
+		return Sum64WithSeed(d.buf[:d.n], d.seed)
+	}
+	acc := d.acc
+	d.digestLong(&acc)
+	return mergeAccs(&acc, d.secret[secretMergeAccs:], d.total*prime64_1)
+}
+
+// digestLong accumulates the buffered data, including the final stripe,
+// into acc without modifying d.
+func (d *Digest) digestLong(acc *[accNb]uint64) {
+	var last [stripeLen]byte
+	if d.n >= stripeLen {
+		consumeStripes(acc, &d.secret, d.stripes, d.buf[:], (d.n-1)/stripeLen)
+		copy(last[:], d.buf[d.n-stripeLen:d.n])
+	} else {
+		// The final stripe straddles the end of the previously
+		// consumed data and the start of the buffer.
+		c := copy(last[:], d.buf[bufSize-(stripeLen-d.n):])
+		copy(last[c:], d.buf[:d.n])
+	}
+	accumulate512(acc, last[:], d.secret[secretSize-stripeLen-secretLastAcc:])
+}
+
+const (
+	magic         = "xxh3\x01"
+	marshaledSize = len(magic) + 8*accNb + 8*2 + bufSize
+)
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (d *Digest) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, marshaledSize)
+	b = append(b, magic...)
+	for _, v := range d.acc {
+		b = appendUint64(b, v)
+	}
+	b = appendUint64(b, d.seed)
+	b = appendUint64(b, d.total)
+	b = append(b, d.buf[:]...)
+	return b, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (d *Digest) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
+		return errors.New("xxh3: invalid hash state identifier")
+	}
+	if len(b) != marshaledSize {
+		return errors.New("xxh3: invalid hash state size")
+	}
+	b = b[len(magic):]
+	for i := range d.acc {
+		b, d.acc[i] = consumeUint64(b)
+	}
+	b, d.seed = consumeUint64(b)
+	initSecret(&d.secret, d.seed)
+	b, d.total = consumeUint64(b)
+	copy(d.buf[:], b)
+	// The buffer is only consumed when it overflows, so it always holds
+	// between 1 and bufSize bytes once anything has been written.
+	d.n = 0
+	if d.total > 0 {
+		d.n = int((d.total-1)%bufSize) + 1
+	}
+	d.stripes = int((d.total - uint64(d.n)) / stripeLen % stripesPerBlock)
+	return nil
+}
+
+func appendUint64(b []byte, x uint64) []byte {
+	var a [8]byte
+	binary.LittleEndian.PutUint64(a[:], x)
+	return append(b, a[:]...)
+}
+
+func consumeUint64(b []byte) ([]byte, uint64) {
+	x := u64(b)
+	return b[8:], x
+}
@@ -0,0 +1,33 @@
+//go:build appengine
+// +build appengine
+
+// This file contains the safe implementations of otherwise unsafe-using code.
+
+package xxh3
+
+// Sum64String computes the 64-bit XXH3 digest of s with a zero seed.
+func Sum64String(s string) uint64 {
+	return Sum64([]byte(s))
+}
+
+// Sum64StringWithSeed computes the 64-bit XXH3 digest of s using the given
+// seed.
+func Sum64StringWithSeed(s string, seed uint64) uint64 {
+	return Sum64WithSeed([]byte(s), seed)
+}
+
+// Sum128String computes the 128-bit XXH3 digest of s with a zero seed.
+func Sum128String(s string) Uint128 {
+	return Sum128([]byte(s))
+}
+
+// Sum128StringWithSeed computes the 128-bit XXH3 digest of s using the given
+// seed.
+func Sum128StringWithSeed(s string, seed uint64) Uint128 {
+	return Sum128WithSeed([]byte(s), seed)
+}
+
+// WriteString adds more data to d. It always returns len(s), nil.
+func (d *Digest) WriteString(s string) (n int, err error) {
+	return d.Write([]byte(s))
+}
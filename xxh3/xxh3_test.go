@@ -0,0 +1,241 @@
+package xxh3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// sanityBuffer is the input used by the reference implementation's sanity
+// checks (see fillTestBuffer in upstream's cli/xsum_sanity_check.c).
+var sanityBuffer = func() []byte {
+	b := make([]byte, 4096)
+	gen := uint64(2654435761)
+	for i := range b {
+		b[i] = byte(gen >> 56)
+		gen *= 11400714785074694797
+	}
+	return b
+}()
+
+const sanitySeed = 11400714785074694797
+
+var testVectors = []struct {
+	n    int
+	seed uint64
+	want uint64
+}{
+	{0, 0, 0x2d06800538d394c2},
+	{1, 0, 0xc44bdff4074eecdb},
+	{2, 0, 0x7a9978044cb8a8bb},
+	{3, 0, 0x54247382a8d6b94d},
+	{4, 0, 0xe5dc74bc51848a51},
+	{6, 0, 0x27b56a84cd2d7325},
+	{8, 0, 0x24ccc9acaa9f65e4},
+	{9, 0, 0x14d5001c15dd3f2b},
+	{12, 0, 0xa713daf0dfbb77e7},
+	{16, 0, 0x981b17d36c7498c9},
+	{17, 0, 0x796f5acd3a60f862},
+	{24, 0, 0xa3fe70bf9d3510eb},
+	{32, 0, 0x9feaddbdbf57eed3},
+	{33, 0, 0xabfb2d081b400a10},
+	{48, 0, 0x397da259ecba1f11},
+	{64, 0, 0x9cb48487720ec49d},
+	{65, 0, 0xfd81aac4bebc3883},
+	{80, 0, 0xbcdefbbb2c47c90a},
+	{96, 0, 0x935a769a7f94776f},
+	{97, 0, 0xca4ca268fd3c3a6c},
+	{112, 0, 0xd13d8f57931eef19},
+	{128, 0, 0xfcff24126754d861},
+	{129, 0, 0x98f1b0a679a2ca29},
+	{195, 0, 0xcd94217ee362ec3a},
+	{240, 0, 0x81c3c2b67f568ccf},
+	{241, 0, 0xc5a639ecd2030e5e},
+	{255, 0, 0xe98f979f4ed8a197},
+	{256, 0, 0x55de574ad89d0ac5},
+	{257, 0, 0xb17fd5a8ae75bb0b},
+	{403, 0, 0xcdeb804d65c6dea4},
+	{512, 0, 0x617e49599013cb6b},
+	{1024, 0, 0xdd85c9b5c1109c5c},
+	{1025, 0, 0xd870c0fa13211c6a},
+	{2048, 0, 0xdd59e2c3a5f038e0},
+	{2240, 0, 0x6e73a90539cf2948},
+	{2367, 0, 0xcb37aeb9e5d361ed},
+	{4096, 0, 0xe91206429d1f48f9},
+
+	{0, sanitySeed, 0xa8a6b918b2f0364a},
+	{1, sanitySeed, 0x032be332dd766ef8},
+	{2, sanitySeed, 0x764b35c90519ad88},
+	{3, sanitySeed, 0x634b8990b4976373},
+	{4, sanitySeed, 0xaa2e7eccb0c8f747},
+	{6, sanitySeed, 0x84589c116ab59ab9},
+	{8, sanitySeed, 0x8f973410999b8f6b},
+	{9, sanitySeed, 0xb3ae7333d9013f60},
+	{12, sanitySeed, 0xe7303e1b2336de0e},
+	{16, sanitySeed, 0x663f29333b4db6b1},
+	{17, sanitySeed, 0xf3ec5067f4306db3},
+	{24, sanitySeed, 0x850e80fc35bdd690},
+	{32, sanitySeed, 0x2199fab1534893d9},
+	{33, sanitySeed, 0xad56348da574bb6d},
+	{48, sanitySeed, 0xadc2cbaa44acc616},
+	{64, sanitySeed, 0x4fe8895db9b8c077},
+	{65, sanitySeed, 0xad80aeec1fc9e0a7},
+	{80, sanitySeed, 0xc6dd0cb699532e73},
+	{96, sanitySeed, 0x70cf51937e500540},
+	{97, sanitySeed, 0xee461d3add7ee6c9},
+	{112, sanitySeed, 0xa276b2e306e77fe5},
+	{128, sanitySeed, 0x73fde75280646649},
+	{129, sanitySeed, 0x21fffdbca099c844},
+	{195, sanitySeed, 0xba68003d370cb3d9},
+	{240, sanitySeed, 0xcc0f58c27ef3d8ee},
+	{241, sanitySeed, 0xdda9b0a161d4829a},
+	{255, sanitySeed, 0x2aca7901d9538c75},
+	{256, sanitySeed, 0x4d30234b7a3aa61c},
+	{257, sanitySeed, 0x802a6fbf3cacd97c},
+	{403, sanitySeed, 0x6259f6ecfd6443fd},
+	{512, sanitySeed, 0x3ce457de14c27708},
+	{1024, sanitySeed, 0xef368a8a2ebabaef},
+	{1025, sanitySeed, 0x96792bcf9af88519},
+	{2048, sanitySeed, 0x66f81670669ababc},
+	{2240, sanitySeed, 0x757ba8487d1b5247},
+	{2367, sanitySeed, 0xd2db3415b942b42a},
+	{4096, sanitySeed, 0x2a3bbb20a5439dcd},
+}
+
+// chunkSizes exercises writes that straddle stripe, buffer, and block
+// boundaries.
+var chunkSizes = []int{1, 3, 16, 63, 64, 65, 100, 255, 256, 257, 1000, 1024, 4096}
+
+func TestAll(t *testing.T) {
+	for _, tt := range testVectors {
+		input := sanityBuffer[:tt.n]
+		name := fmt.Sprintf("len=%d", tt.n)
+		if tt.seed != 0 {
+			name += fmt.Sprintf(",seed=%d", tt.seed)
+		}
+		t.Run(name, func(t *testing.T) {
+			testSum(t, input, tt.seed, tt.want)
+			for _, chunkSize := range chunkSizes {
+				testDigest(t, input, tt.seed, chunkSize, tt.want)
+			}
+		})
+	}
+}
+
+func testSum(t *testing.T, input []byte, seed, want uint64) {
+	t.Helper()
+	if got := Sum64WithSeed(input, seed); got != want {
+		t.Fatalf("Sum64WithSeed: got 0x%x; want 0x%x", got, want)
+	}
+	if got := Sum64StringWithSeed(string(input), seed); got != want {
+		t.Fatalf("Sum64StringWithSeed: got 0x%x; want 0x%x", got, want)
+	}
+	if seed != 0 {
+		return
+	}
+	if got := Sum64(input); got != want {
+		t.Fatalf("Sum64: got 0x%x; want 0x%x", got, want)
+	}
+	if got := Sum64String(string(input)); got != want {
+		t.Fatalf("Sum64String: got 0x%x; want 0x%x", got, want)
+	}
+}
+
+func testDigest(t *testing.T, input []byte, seed uint64, chunkSize int, want uint64) {
+	t.Helper()
+	d := NewWithSeed(seed)
+	ds := NewWithSeed(seed) // uses WriteString
+	for i := 0; i < len(input); i += chunkSize {
+		chunk := input[i:]
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		n, err := d.Write(chunk)
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Digest.Write: got (%d, %v); want (%d, nil)", n, err, len(chunk))
+		}
+		n, err = ds.WriteString(string(chunk))
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Digest.WriteString: got (%d, %v); want (%d, nil)", n, err, len(chunk))
+		}
+	}
+	if got := d.Sum64(); got != want {
+		t.Fatalf("chunkSize=%d: Digest.Sum64: got 0x%x; want 0x%x", chunkSize, got, want)
+	}
+	if got := ds.Sum64(); got != want {
+		t.Fatalf("chunkSize=%d: Digest.Sum64 (WriteString): got 0x%x; want 0x%x", chunkSize, got, want)
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], want)
+	if got := d.Sum(nil); !bytes.Equal(got, b[:]) {
+		t.Fatalf("chunkSize=%d: Sum: got %v; want %v", chunkSize, got, b[:])
+	}
+}
+
+func TestReset(t *testing.T) {
+	d := NewWithSeed(123)
+	d.Write(sanityBuffer[:3000])
+	d.Reset()
+	d.Write(sanityBuffer[:100])
+	if got, want := d.Sum64(), Sum64(sanityBuffer[:100]); got != want {
+		t.Fatalf("after Reset: got 0x%x; want 0x%x", got, want)
+	}
+	d.ResetWithSeed(sanitySeed)
+	d.Write(sanityBuffer[:2367])
+	if got, want := d.Sum64(), Sum64WithSeed(sanityBuffer[:2367], sanitySeed); got != want {
+		t.Fatalf("after ResetWithSeed: got 0x%x; want 0x%x", got, want)
+	}
+}
+
+func TestBinaryMarshaling(t *testing.T) {
+	for _, seed := range []uint64{0, sanitySeed} {
+		d0 := NewWithSeed(seed)
+		d1 := NewWithSeed(seed)
+		for i := 0; i < 1500; i += 7 {
+			b, err := d0.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			d0 = new(Digest)
+			if err := d0.UnmarshalBinary(b); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := d0.Sum64(), d1.Sum64(); got != want {
+				t.Fatalf("seed=%d: after writing %d bytes, unmarshaled Digest gave sum 0x%x; want 0x%x", seed, i, got, want)
+			}
+			d0.Write(sanityBuffer[i : i+7])
+			d1.Write(sanityBuffer[i : i+7])
+		}
+	}
+
+	d := New()
+	if err := d.UnmarshalBinary([]byte("xxh\x06")); err == nil {
+		t.Fatal("UnmarshalBinary accepted an XXH64 state identifier")
+	}
+}
+
+var sink uint64
+
+func TestAllocs(t *testing.T) {
+	b := sanityBuffer[:1000]
+	t.Run("Sum64WithSeed", func(t *testing.T) {
+		testAllocs(t, func() {
+			sink = Sum64WithSeed(b, 123)
+		})
+	})
+	t.Run("Digest", func(t *testing.T) {
+		testAllocs(t, func() {
+			d := New()
+			d.Write(b)
+			sink = d.Sum64()
+		})
+	})
+}
+
+func testAllocs(t *testing.T, fn func()) {
+	t.Helper()
+	if allocs := int(testing.AllocsPerRun(10, fn)); allocs > 0 {
+		t.Fatalf("got %d allocation(s) (want zero)", allocs)
+	}
+}
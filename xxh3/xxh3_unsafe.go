@@ -0,0 +1,58 @@
+//go:build !appengine
+// +build !appengine
+
+// This file encapsulates usage of unsafe.
+// xxh3_safe.go contains the safe implementations.
+// See xxhash_unsafe.go in the parent package for a discussion of the
+// string-to-[]byte conversion used here.
+
+package xxh3
+
+import (
+	"unsafe"
+)
+
+// Sum64String computes the 64-bit XXH3 digest of s with a zero seed.
+// It may be faster than Sum64([]byte(s)) by avoiding a copy.
+func Sum64String(s string) uint64 {
+	b := *(*[]byte)(unsafe.Pointer(&sliceHeader{s, len(s)}))
+	return Sum64(b)
+}
+
+// Sum64StringWithSeed computes the 64-bit XXH3 digest of s using the given
+// seed. It may be faster than Sum64WithSeed([]byte(s), seed) by avoiding a
+// copy.
+func Sum64StringWithSeed(s string, seed uint64) uint64 {
+	b := *(*[]byte)(unsafe.Pointer(&sliceHeader{s, len(s)}))
+	return Sum64WithSeed(b, seed)
+}
+
+// Sum128String computes the 128-bit XXH3 digest of s with a zero seed.
+// It may be faster than Sum128([]byte(s)) by avoiding a copy.
+func Sum128String(s string) Uint128 {
+	b := *(*[]byte)(unsafe.Pointer(&sliceHeader{s, len(s)}))
+	return Sum128(b)
+}
+
+// Sum128StringWithSeed computes the 128-bit XXH3 digest of s using the given
+// seed. It may be faster than Sum128WithSeed([]byte(s), seed) by avoiding a
+// copy.
+func Sum128StringWithSeed(s string, seed uint64) Uint128 {
+	b := *(*[]byte)(unsafe.Pointer(&sliceHeader{s, len(s)}))
+	return Sum128WithSeed(b, seed)
+}
+
+// WriteString adds more data to d. It always returns len(s), nil.
+// It may be faster than Write([]byte(s)) by avoiding a copy.
+func (d *Digest) WriteString(s string) (n int, err error) {
+	d.Write(*(*[]byte)(unsafe.Pointer(&sliceHeader{s, len(s)})))
+	// d.Write always returns len(s), nil.
+	return len(s), nil
+}
+
+// sliceHeader is similar to reflect.SliceHeader, but it assumes that the layout
+// of the first two words is the same as the layout of a string.
+type sliceHeader struct {
+	s   string
+	cap int
+}
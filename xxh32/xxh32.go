@@ -0,0 +1,238 @@
+// Package xxh32 implements the 32-bit variant of xxHash (XXH32) as described
+// at https://xxhash.com/.
+//
+// XXH32 is mostly useful for interoperating with existing formats that use it,
+// such as the LZ4 frame format. For new uses, XXH64 (the parent xxhash package)
+// or XXH3 (the xxh3 package) are faster on 64-bit platforms.
+package xxh32
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+const (
+	prime1 uint32 = 2654435761
+	prime2 uint32 = 2246822519
+	prime3 uint32 = 3266489917
+	prime4 uint32 = 668265263
+	prime5 uint32 = 374761393
+)
+
+// Sum32 computes the 32-bit xxHash digest of b with a zero seed.
+func Sum32(b []byte) uint32 {
+	return Sum32WithSeed(b, 0)
+}
+
+// Digest implements hash.Hash32.
+//
+// Note that a zero-valued Digest is not ready to receive writes.
+// Call Reset or create a Digest using New before calling other methods.
+type Digest struct {
+	v1    uint32
+	v2    uint32
+	v3    uint32
+	v4    uint32
+	seed  uint32
+	total uint64
+	mem   [16]byte
+	n     int // how much of mem is used
+}
+
+// New creates a new Digest with a zero seed.
+func New() *Digest {
+	return NewWithSeed(0)
+}
+
+// NewWithSeed creates a new Digest with the given seed.
+func NewWithSeed(seed uint32) *Digest {
+	var d Digest
+	d.ResetWithSeed(seed)
+	return &d
+}
+
+// Reset clears the Digest's state so that it can be reused.
+// It uses a seed value of zero.
+func (d *Digest) Reset() {
+	d.ResetWithSeed(0)
+}
+
+// ResetWithSeed clears the Digest's state so that it can be reused.
+// It uses the given seed to initialize the state.
+func (d *Digest) ResetWithSeed(seed uint32) {
+	d.v1 = seed + prime1 + prime2
+	d.v2 = seed + prime2
+	d.v3 = seed
+	d.v4 = seed - prime1
+	d.seed = seed
+	d.total = 0
+	d.n = 0
+}
+
+// Size always returns 4 bytes.
+func (d *Digest) Size() int { return 4 }
+
+// BlockSize always returns 16 bytes.
+func (d *Digest) BlockSize() int { return 16 }
+
+// Write adds more data to d. It always returns len(b), nil.
+func (d *Digest) Write(b []byte) (n int, err error) {
+	n = len(b)
+	d.total += uint64(n)
+
+	memleft := d.mem[d.n&(len(d.mem)-1):]
+
+	if d.n+n < 16 {
+		// This new data doesn't even fill the current block.
+		copy(memleft, b)
+		d.n += n
+		return
+	}
+
+	if d.n > 0 {
+		// Finish off the partial block.
+		c := copy(memleft, b)
+		d.v1 = round(d.v1, u32(d.mem[0:4]))
+		d.v2 = round(d.v2, u32(d.mem[4:8]))
+		d.v3 = round(d.v3, u32(d.mem[8:12]))
+		d.v4 = round(d.v4, u32(d.mem[12:16]))
+		b = b[c:]
+		d.n = 0
+	}
+
+	if len(b) >= 16 {
+		// One or more full blocks left.
+		nw := writeBlocks(d, b)
+		b = b[nw:]
+	}
+
+	// Store any remaining partial block.
+	copy(d.mem[:], b)
+	d.n = len(b)
+
+	return
+}
+
+// Sum appends the current hash to b and returns the resulting slice.
+func (d *Digest) Sum(b []byte) []byte {
+	s := d.Sum32()
+	return append(
+		b,
+		byte(s>>24),
+		byte(s>>16),
+		byte(s>>8),
+		byte(s),
+	)
+}
+
+// Sum32 returns the current hash.
+func (d *Digest) Sum32() uint32 {
+	var h uint32
+
+	if d.total >= 16 {
+		h = rol1(d.v1) + rol7(d.v2) + rol12(d.v3) + rol18(d.v4)
+	} else {
+		h = d.seed + prime5
+	}
+
+	// XXH32 only mixes in the low 32 bits of the length.
+	h += uint32(d.total)
+
+	b := d.mem[:d.n&(len(d.mem)-1)]
+	for ; len(b) >= 4; b = b[4:] {
+		h += u32(b[:4]) * prime3
+		h = rol17(h) * prime4
+	}
+	for ; len(b) > 0; b = b[1:] {
+		h += uint32(b[0]) * prime5
+		h = rol11(h) * prime1
+	}
+
+	h ^= h >> 15
+	h *= prime2
+	h ^= h >> 13
+	h *= prime3
+	h ^= h >> 16
+
+	return h
+}
+
+const (
+	magic         = "xxh32\x01"
+	marshaledSize = len(magic) + 4*5 + 8 + 16
+)
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (d *Digest) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, marshaledSize)
+	b = append(b, magic...)
+	b = appendUint32(b, d.v1)
+	b = appendUint32(b, d.v2)
+	b = appendUint32(b, d.v3)
+	b = appendUint32(b, d.v4)
+	b = appendUint32(b, d.seed)
+	b = appendUint64(b, d.total)
+	b = append(b, d.mem[:d.n]...)
+	b = b[:len(b)+len(d.mem)-d.n]
+	return b, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (d *Digest) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
+		return errors.New("xxh32: invalid hash state identifier")
+	}
+	if len(b) != marshaledSize {
+		return errors.New("xxh32: invalid hash state size")
+	}
+	b = b[len(magic):]
+	b, d.v1 = consumeUint32(b)
+	b, d.v2 = consumeUint32(b)
+	b, d.v3 = consumeUint32(b)
+	b, d.v4 = consumeUint32(b)
+	b, d.seed = consumeUint32(b)
+	b, d.total = consumeUint64(b)
+	copy(d.mem[:], b)
+	d.n = int(d.total % uint64(len(d.mem)))
+	return nil
+}
+
+func appendUint32(b []byte, x uint32) []byte {
+	var a [4]byte
+	binary.LittleEndian.PutUint32(a[:], x)
+	return append(b, a[:]...)
+}
+
+func appendUint64(b []byte, x uint64) []byte {
+	var a [8]byte
+	binary.LittleEndian.PutUint64(a[:], x)
+	return append(b, a[:]...)
+}
+
+func consumeUint32(b []byte) ([]byte, uint32) {
+	x := u32(b)
+	return b[4:], x
+}
+
+func consumeUint64(b []byte) ([]byte, uint64) {
+	x := binary.LittleEndian.Uint64(b)
+	return b[8:], x
+}
+
+func u32(b []byte) uint32 { return binary.LittleEndian.Uint32(b) }
+
+func round(acc, input uint32) uint32 {
+	acc += input * prime2
+	acc = rol13(acc)
+	acc *= prime1
+	return acc
+}
+
+func rol1(x uint32) uint32  { return bits.RotateLeft32(x, 1) }
+func rol7(x uint32) uint32  { return bits.RotateLeft32(x, 7) }
+func rol11(x uint32) uint32 { return bits.RotateLeft32(x, 11) }
+func rol12(x uint32) uint32 { return bits.RotateLeft32(x, 12) }
+func rol13(x uint32) uint32 { return bits.RotateLeft32(x, 13) }
+func rol17(x uint32) uint32 { return bits.RotateLeft32(x, 17) }
+func rol18(x uint32) uint32 { return bits.RotateLeft32(x, 18) }
@@ -0,0 +1,182 @@
+//go:build !appengine && gc && !purego
+// +build !appengine
+// +build gc
+// +build !purego
+
+#include "textflag.h"
+
+#define PRIME1 $2654435761
+#define PRIME2 $2246822519
+#define PRIME3 $3266489917
+#define PRIME4 $668265263
+#define PRIME5 $374761393
+
+// Registers:
+#define h      AX
+#define digest AX
+#define p      SI // pointer to advance through b
+#define n      DX
+#define end    BX // loop end
+#define v1     R8
+#define v2     R9
+#define v3     R10
+#define v4     R11
+#define x      R12
+#define prime1 R13
+#define prime2 R14
+
+#define round(acc, x) \
+	IMULL prime2, x   \
+	ADDL  x, acc      \
+	ROLL  $13, acc    \
+	IMULL prime1, acc
+
+// blockLoop processes as many 16-byte blocks as possible,
+// updating v1, v2, v3, and v4. It assumes that there is at least one block
+// to process.
+#define blockLoop() \
+loop:  \
+	MOVL +0(p), x  \
+	round(v1, x)   \
+	MOVL +4(p), x  \
+	round(v2, x)   \
+	MOVL +8(p), x  \
+	round(v3, x)   \
+	MOVL +12(p), x \
+	round(v4, x)   \
+	ADDQ $16, p    \
+	CMPQ p, end    \
+	JLE  loop
+
+// func Sum32WithSeed(b []byte, seed uint32) uint32
+TEXT ·Sum32WithSeed(SB), NOSPLIT|NOFRAME, $0-36
+	// Load fixed primes.
+	MOVL PRIME1, prime1
+	MOVL PRIME2, prime2
+
+	// Load slice and seed.
+	MOVQ b_base+0(FP), p
+	MOVQ b_len+8(FP), n
+	MOVL seed+24(FP), h
+	LEAQ (p)(n*1), end
+
+	// The first loop limit will be len(b)-16.
+	SUBQ $16, end
+
+	// Check whether we have at least one block.
+	CMPQ n, $16
+	JLT  noBlocks
+
+	// Set up initial state (v1, v2, v3, v4).
+	MOVL h, v1
+	ADDL prime1, v1
+	ADDL prime2, v1
+	MOVL h, v2
+	ADDL prime2, v2
+	MOVL h, v3
+	MOVL h, v4
+	SUBL prime1, v4
+
+	blockLoop()
+
+	MOVL v1, h
+	ROLL $1, h
+	MOVL v2, x
+	ROLL $7, x
+	ADDL x, h
+	MOVL v3, x
+	ROLL $12, x
+	ADDL x, h
+	MOVL v4, x
+	ROLL $18, x
+	ADDL x, h
+
+	JMP afterBlocks
+
+noBlocks:
+	ADDL PRIME5, h
+
+afterBlocks:
+	ADDL n, h
+
+	ADDQ $12, end
+	CMPQ p, end
+	JG   try1
+
+loop4:
+	MOVL   (p), x
+	ADDQ   $4, p
+	IMUL3L PRIME3, x, x
+	ADDL   x, h
+	ROLL   $17, h
+	IMUL3L PRIME4, h, h
+
+	CMPQ p, end
+	JLE  loop4
+
+try1:
+	ADDQ $4, end
+	CMPQ p, end
+	JGE  finalize
+
+loop1:
+	MOVBLZX (p), x
+	ADDQ    $1, p
+	IMUL3L  PRIME5, x, x
+	ADDL    x, h
+	ROLL    $11, h
+	IMULL   prime1, h
+
+	CMPQ p, end
+	JL   loop1
+
+finalize:
+	MOVL   h, x
+	SHRL   $15, x
+	XORL   x, h
+	IMULL  prime2, h
+	MOVL   h, x
+	SHRL   $13, x
+	XORL   x, h
+	IMUL3L PRIME3, h, h
+	MOVL   h, x
+	SHRL   $16, x
+	XORL   x, h
+
+	MOVL h, ret+32(FP)
+	RET
+
+// func writeBlocks(d *Digest, b []byte) int
+TEXT ·writeBlocks(SB), NOSPLIT|NOFRAME, $0-40
+	// Load fixed primes needed for round.
+	MOVL PRIME1, prime1
+	MOVL PRIME2, prime2
+
+	// Load slice.
+	MOVQ b_base+8(FP), p
+	MOVQ b_len+16(FP), n
+	LEAQ (p)(n*1), end
+	SUBQ $16, end
+
+	// Load vN from d.
+	MOVQ d+0(FP), digest
+	MOVL 0(digest), v1
+	MOVL 4(digest), v2
+	MOVL 8(digest), v3
+	MOVL 12(digest), v4
+
+	// We don't need to check the loop condition here; this function is
+	// always called with at least one block of data to process.
+	blockLoop()
+
+	// Copy vN back to d.
+	MOVL v1, 0(digest)
+	MOVL v2, 4(digest)
+	MOVL v3, 8(digest)
+	MOVL v4, 12(digest)
+
+	// The number of bytes written is p minus the old base pointer.
+	SUBQ b_base+8(FP), p
+	MOVQ p, ret+32(FP)
+
+	RET
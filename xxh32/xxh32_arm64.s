@@ -0,0 +1,159 @@
+//go:build !appengine && gc && !purego
+// +build !appengine
+// +build gc
+// +build !purego
+
+#include "textflag.h"
+
+// Registers:
+#define digest	R1
+#define h	R2 // return value
+#define p	R3 // input pointer
+#define n	R4 // input length
+#define nblocks	R5 // n / 16
+#define prime1	R7
+#define prime2	R8
+#define prime3	R9
+#define prime4	R10
+#define prime5	R11
+#define v1	R12
+#define v2	R13
+#define v3	R14
+#define v4	R15
+#define x1	R20
+#define x2	R21
+#define x3	R22
+#define x4	R23
+
+// All arithmetic below uses the 32-bit (W) forms of the instructions.
+
+#define round(acc, x) \
+	MADDW prime2, acc, x, acc \
+	RORW  $32-13, acc         \
+	MULW  prime1, acc
+
+// blockLoop processes as many 16-byte blocks as possible,
+// updating v1, v2, v3, and v4. It assumes that n >= 16.
+#define blockLoop() \
+	LSR     $4, n, nblocks \
+	PCALIGN $16            \
+	loop:                  \
+	LDPW.P  8(p), (x1, x2) \
+	LDPW.P  8(p), (x3, x4) \
+	round(v1, x1)          \
+	round(v2, x2)          \
+	round(v3, x3)          \
+	round(v4, x4)          \
+	SUB     $1, nblocks    \
+	CBNZ    nblocks, loop
+
+// func Sum32WithSeed(b []byte, seed uint32) uint32
+TEXT ·Sum32WithSeed(SB), NOSPLIT|NOFRAME, $0-36
+	LDP   b_base+0(FP), (p, n)
+	MOVWU seed+24(FP), h
+
+	MOVD $2654435761, prime1
+	MOVD $2246822519, prime2
+	MOVD $3266489917, prime3
+	MOVD $668265263, prime4
+	MOVD $374761393, prime5
+
+	CMP $16, n
+	BLT noBlocks
+
+	ADDW prime1, h, v1
+	ADDW prime2, v1
+	ADDW prime2, h, v2
+	MOVW h, v3
+	SUBW prime1, h, v4
+
+	blockLoop()
+
+	RORW $32-1, v1, x1
+	RORW $32-7, v2, x2
+	ADDW x1, x2
+	RORW $32-12, v3, x3
+	RORW $32-18, v4, x4
+	ADDW x3, x4
+	ADDW x2, x4, h
+	B    afterLoop
+
+noBlocks:
+	ADDW prime5, h
+
+afterLoop:
+	ADDW n, h
+
+	TBZ     $3, n, try4
+	MOVWU.P 4(p), x1
+	MOVWU.P 4(p), x2
+
+	MADDW prime3, h, x1, h
+	RORW  $32-17, h
+	MULW  prime4, h
+
+	MADDW prime3, h, x2, h
+	RORW  $32-17, h
+	MULW  prime4, h
+
+try4:
+	TBZ     $2, n, try2
+	MOVWU.P 4(p), x1
+
+	MADDW prime3, h, x1, h
+	RORW  $32-17, h
+	MULW  prime4, h
+
+try2:
+	TBZ     $1, n, try1
+	MOVHU.P 2(p), x3
+	AND     $255, x3, x1
+	LSR     $8, x3, x2
+
+	MADDW prime5, h, x1, h
+	RORW  $32-11, h
+	MULW  prime1, h
+
+	MADDW prime5, h, x2, h
+	RORW  $32-11, h
+	MULW  prime1, h
+
+try1:
+	TBZ   $0, n, finalize
+	MOVBU (p), x4
+
+	MADDW prime5, h, x4, h
+	RORW  $32-11, h
+	MULW  prime1, h
+
+finalize:
+	EORW h >> 15, h
+	MULW prime2, h
+	EORW h >> 13, h
+	MULW prime3, h
+	EORW h >> 16, h
+
+	MOVW h, ret+32(FP)
+	RET
+
+// func writeBlocks(d *Digest, b []byte) int
+TEXT ·writeBlocks(SB), NOSPLIT|NOFRAME, $0-40
+	MOVD $2654435761, prime1
+	MOVD $2246822519, prime2
+
+	// Load state. Assume v[1-4] are stored contiguously.
+	MOVD d+0(FP), digest
+	LDPW 0(digest), (v1, v2)
+	LDPW 8(digest), (v3, v4)
+
+	LDP b_base+8(FP), (p, n)
+
+	blockLoop()
+
+	// Store updated state.
+	STPW (v1, v2), 0(digest)
+	STPW (v3, v4), 8(digest)
+
+	BIC  $15, n
+	MOVD n, ret+32(FP)
+	RET
@@ -0,0 +1,15 @@
+//go:build (amd64 || arm64) && !appengine && gc && !purego
+// +build amd64 arm64
+// +build !appengine
+// +build gc
+// +build !purego
+
+package xxh32
+
+// Sum32WithSeed computes the 32-bit xxHash digest of b using the given seed.
+//
+//go:noescape
+func Sum32WithSeed(b []byte, seed uint32) uint32
+
+//go:noescape
+func writeBlocks(d *Digest, b []byte) int
@@ -0,0 +1,60 @@
+//go:build (!amd64 && !arm64) || appengine || !gc || purego
+// +build !amd64,!arm64 appengine !gc purego
+
+package xxh32
+
+// Sum32WithSeed computes the 32-bit xxHash digest of b using the given seed.
+func Sum32WithSeed(b []byte, seed uint32) uint32 {
+	n := len(b)
+	var h uint32
+
+	if n >= 16 {
+		v1 := seed + prime1 + prime2
+		v2 := seed + prime2
+		v3 := seed
+		v4 := seed - prime1
+		for len(b) >= 16 {
+			v1 = round(v1, u32(b[0:4:len(b)]))
+			v2 = round(v2, u32(b[4:8:len(b)]))
+			v3 = round(v3, u32(b[8:12:len(b)]))
+			v4 = round(v4, u32(b[12:16:len(b)]))
+			b = b[16:len(b):len(b)]
+		}
+		h = rol1(v1) + rol7(v2) + rol12(v3) + rol18(v4)
+	} else {
+		h = seed + prime5
+	}
+
+	h += uint32(n)
+
+	for ; len(b) >= 4; b = b[4:] {
+		h += u32(b[:4]) * prime3
+		h = rol17(h) * prime4
+	}
+	for ; len(b) > 0; b = b[1:] {
+		h += uint32(b[0]) * prime5
+		h = rol11(h) * prime1
+	}
+
+	h ^= h >> 15
+	h *= prime2
+	h ^= h >> 13
+	h *= prime3
+	h ^= h >> 16
+
+	return h
+}
+
+func writeBlocks(d *Digest, b []byte) int {
+	v1, v2, v3, v4 := d.v1, d.v2, d.v3, d.v4
+	n := len(b)
+	for len(b) >= 16 {
+		v1 = round(v1, u32(b[0:4:len(b)]))
+		v2 = round(v2, u32(b[4:8:len(b)]))
+		v3 = round(v3, u32(b[8:12:len(b)]))
+		v4 = round(v4, u32(b[12:16:len(b)]))
+		b = b[16:len(b):len(b)]
+	}
+	d.v1, d.v2, d.v3, d.v4 = v1, v2, v3, v4
+	return n - len(b)
+}
@@ -0,0 +1,22 @@
+//go:build appengine
+// +build appengine
+
+// This file contains the safe implementations of otherwise unsafe-using code.
+
+package xxh32
+
+// Sum32String computes the 32-bit xxHash digest of s with a zero seed.
+func Sum32String(s string) uint32 {
+	return Sum32([]byte(s))
+}
+
+// Sum32StringWithSeed computes the 32-bit xxHash digest of s using the given
+// seed.
+func Sum32StringWithSeed(s string, seed uint32) uint32 {
+	return Sum32WithSeed([]byte(s), seed)
+}
+
+// WriteString adds more data to d. It always returns len(s), nil.
+func (d *Digest) WriteString(s string) (n int, err error) {
+	return d.Write([]byte(s))
+}
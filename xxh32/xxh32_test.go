@@ -0,0 +1,236 @@
+package xxh32
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// sanityBuffer is the input used by the reference implementation's sanity
+// checks (see fillTestBuffer in upstream's cli/xsum_sanity_check.c).
+var sanityBuffer = func() []byte {
+	b := make([]byte, 4096)
+	gen := uint64(2654435761)
+	for i := range b {
+		b[i] = byte(gen >> 56)
+		gen *= 11400714785074694797
+	}
+	return b
+}()
+
+func TestAll(t *testing.T) {
+	for _, tt := range []struct {
+		n    int
+		seed uint32
+		want uint32
+	}{
+		{0, 0, 0x02cc5d05},
+		{1, 0, 0xcf65b03e},
+		{2, 0, 0x1151bee4},
+		{3, 0, 0xc23884f5},
+		{4, 0, 0xa9de7ce9},
+		{5, 0, 0xeb1734bb},
+		{7, 0, 0x5e1056cd},
+		{8, 0, 0xa3f6f44b},
+		{14, 0, 0x1208e7e2},
+		{15, 0, 0x6b859e14},
+		{16, 0, 0x93ba3759},
+		{17, 0, 0x89fdc23e},
+		{19, 0, 0x858fc8ea},
+		{31, 0, 0x5f40e562},
+		{32, 0, 0xd89829ec},
+		{33, 0, 0x31a427e5},
+		{47, 0, 0xd5e9364e},
+		{48, 0, 0xbfd05cbd},
+		{63, 0, 0xf1d48fdb},
+		{100, 0, 0x96ad8143},
+		{222, 0, 0x5bd11dbd},
+		{1024, 0, 0xc08e0a35},
+		{4096, 0, 0x20fc444f},
+
+		{0, prime1, 0x36b78ae7},
+		{1, prime1, 0xb4545aa4},
+		{2, prime1, 0x1edb879a},
+		{3, prime1, 0x1a269947},
+		{4, prime1, 0x2baafe83},
+		{5, prime1, 0x5874dab0},
+		{7, prime1, 0x3ed9d3fc},
+		{8, prime1, 0xc2a8e239},
+		{14, prime1, 0x6af1d1fe},
+		{15, prime1, 0xad53090d},
+		{16, prime1, 0xa94fc1e1},
+		{17, prime1, 0xc9910739},
+		{19, prime1, 0x63826a8f},
+		{31, prime1, 0x5c0c3350},
+		{32, prime1, 0xa5c44467},
+		{33, prime1, 0x0de5b1f9},
+		{47, prime1, 0xa463c5a4},
+		{48, prime1, 0x0eccc06e},
+		{63, prime1, 0x956b3d77},
+		{100, prime1, 0x83d48124},
+		{222, prime1, 0x58803c5f},
+		{1024, prime1, 0x1d62ea25},
+		{4096, prime1, 0x102ad417},
+	} {
+		input := string(sanityBuffer[:tt.n])
+		name := fmt.Sprintf("len=%d", tt.n)
+		if tt.seed != 0 {
+			name += fmt.Sprintf(",seed=%d", tt.seed)
+		}
+		lastChunkSize := len(input)
+		if lastChunkSize > 64 {
+			lastChunkSize = 64
+		}
+		if lastChunkSize == 0 {
+			lastChunkSize = 1
+		}
+		t.Run(name, func(t *testing.T) {
+			testSum(t, input, tt.seed, tt.want)
+			for chunkSize := 1; chunkSize <= lastChunkSize; chunkSize++ {
+				testDigest(t, input, tt.seed, chunkSize, tt.want)
+			}
+		})
+	}
+}
+
+func testSum(t *testing.T, input string, seed, want uint32) {
+	t.Helper()
+	if got := Sum32WithSeed([]byte(input), seed); got != want {
+		t.Fatalf("Sum32WithSeed: got 0x%x; want 0x%x", got, want)
+	}
+	if got := Sum32StringWithSeed(input, seed); got != want {
+		t.Fatalf("Sum32StringWithSeed: got 0x%x; want 0x%x", got, want)
+	}
+	if seed != 0 {
+		return
+	}
+	if got := Sum32([]byte(input)); got != want {
+		t.Fatalf("Sum32: got 0x%x; want 0x%x", got, want)
+	}
+	if got := Sum32String(input); got != want {
+		t.Fatalf("Sum32String: got 0x%x; want 0x%x", got, want)
+	}
+}
+
+func testDigest(t *testing.T, input string, seed uint32, chunkSize int, want uint32) {
+	t.Helper()
+	d := NewWithSeed(seed)
+	ds := NewWithSeed(seed) // uses WriteString
+	for i := 0; i < len(input); i += chunkSize {
+		chunk := input[i:]
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		n, err := d.Write([]byte(chunk))
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Digest.Write: got (%d, %v); want (%d, nil)", n, err, len(chunk))
+		}
+		n, err = ds.WriteString(chunk)
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Digest.WriteString: got (%d, %v); want (%d, nil)", n, err, len(chunk))
+		}
+	}
+	if got := d.Sum32(); got != want {
+		t.Fatalf("chunkSize=%d: Digest.Sum32: got 0x%x; want 0x%x", chunkSize, got, want)
+	}
+	if got := ds.Sum32(); got != want {
+		t.Fatalf("chunkSize=%d: Digest.Sum32 (WriteString): got 0x%x; want 0x%x", chunkSize, got, want)
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], want)
+	if got := d.Sum(nil); !bytes.Equal(got, b[:]) {
+		t.Fatalf("chunkSize=%d: Sum: got %v; want %v", chunkSize, got, b[:])
+	}
+}
+
+func TestReset(t *testing.T) {
+	parts := []string{"The quic", "k br", "o", "wn fox jumps", " ov", "er the lazy ", "dog."}
+	d := NewWithSeed(123)
+	for _, part := range parts {
+		d.Write([]byte(part))
+	}
+	h0 := d.Sum32()
+
+	d.ResetWithSeed(123)
+	d.Write([]byte(strings.Join(parts, "")))
+	h1 := d.Sum32()
+
+	if h0 != h1 {
+		t.Errorf("0x%x != 0x%x", h0, h1)
+	}
+
+	d.Reset()
+	d.WriteString("abc")
+	if got, want := d.Sum32(), uint32(0x32d153ff); got != want {
+		t.Errorf("after Reset: got 0x%x; want 0x%x", got, want)
+	}
+}
+
+func TestBinaryMarshaling(t *testing.T) {
+	d := NewWithSeed(99)
+	d.WriteString("abc")
+	b, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = New()
+	d.WriteString("junk")
+	if err := d.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	d.WriteString("def")
+	if got, want := d.Sum32(), Sum32StringWithSeed("abcdef", 99); got != want {
+		t.Fatalf("after MarshalBinary+UnmarshalBinary, got 0x%x; want 0x%x", got, want)
+	}
+
+	d0 := New()
+	d1 := New()
+	for i := 0; i < 40; i++ {
+		b, err := d0.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d0 = new(Digest)
+		if err := d0.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := d0.Sum32(), d1.Sum32(); got != want {
+			t.Fatalf("after %d Writes, unmarshaled Digest gave sum 0x%x; want 0x%x", i, got, want)
+		}
+
+		d0.Write([]byte{'a'})
+		d1.Write([]byte{'a'})
+	}
+
+	if err := d.UnmarshalBinary([]byte("xxh\x06")); err == nil {
+		t.Fatal("UnmarshalBinary accepted an XXH64 state identifier")
+	}
+}
+
+var sink uint32
+
+func TestAllocs(t *testing.T) {
+	const shortStr = "abcdefghijklmnop"
+	t.Run("Sum32", func(t *testing.T) {
+		testAllocs(t, func() {
+			sink = Sum32([]byte(shortStr))
+		})
+	})
+	t.Run("Digest", func(t *testing.T) {
+		b := []byte("asdf")
+		testAllocs(t, func() {
+			d := New()
+			d.Write(b)
+			sink = d.Sum32()
+		})
+	})
+}
+
+func testAllocs(t *testing.T, fn func()) {
+	t.Helper()
+	if allocs := int(testing.AllocsPerRun(10, fn)); allocs > 0 {
+		t.Fatalf("got %d allocation(s) (want zero)", allocs)
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !appengine
+// +build !appengine
+
+// This file encapsulates usage of unsafe.
+// xxh32_safe.go contains the safe implementations.
+// See xxhash_unsafe.go in the parent package for a discussion of the
+// string-to-[]byte conversion used here.
+
+package xxh32
+
+import (
+	"unsafe"
+)
+
+// Sum32String computes the 32-bit xxHash digest of s with a zero seed.
+// It may be faster than Sum32([]byte(s)) by avoiding a copy.
+func Sum32String(s string) uint32 {
+	b := *(*[]byte)(unsafe.Pointer(&sliceHeader{s, len(s)}))
+	return Sum32WithSeed(b, 0)
+}
+
+// Sum32StringWithSeed computes the 32-bit xxHash digest of s using the given
+// seed. It may be faster than Sum32WithSeed([]byte(s), seed) by avoiding a
+// copy.
+func Sum32StringWithSeed(s string, seed uint32) uint32 {
+	b := *(*[]byte)(unsafe.Pointer(&sliceHeader{s, len(s)}))
+	return Sum32WithSeed(b, seed)
+}
+
+// WriteString adds more data to d. It always returns len(s), nil.
+// It may be faster than Write([]byte(s)) by avoiding a copy.
+func (d *Digest) WriteString(s string) (n int, err error) {
+	d.Write(*(*[]byte)(unsafe.Pointer(&sliceHeader{s, len(s)})))
+	// d.Write always returns len(s), nil.
+	return len(s), nil
+}
+
+// sliceHeader is similar to reflect.SliceHeader, but it assumes that the layout
+// of the first two words is the same as the layout of a string.
+type sliceHeader struct {
+	s   string
+	cap int
+}
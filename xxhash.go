@@ -4,8 +4,10 @@ package xxhash
 
 import (
 	"encoding/binary"
-	"errors"
+	"io"
+	"math"
 	"math/bits"
+	"sync"
 )
 
 const (
@@ -24,9 +26,11 @@ var primes = [...]uint64{prime1, prime2, prime3, prime4, prime5}
 
 // Digest implements hash.Hash64.
 //
-// Note that a zero-valued Digest is not ready to receive writes.
-// Call Reset or create a Digest using New before calling other methods.
+// The zero value of Digest is ready to use and is equivalent to the result
+// of New: it hashes with a zero seed.
 type Digest struct {
+	// The assembly implementations of writeBlocks and digestSum64 depend
+	// on the offsets of v1-v4 and total.
 	v1    uint64
 	v2    uint64
 	v3    uint64
@@ -34,6 +38,13 @@ type Digest struct {
 	total uint64
 	mem   [32]byte
 	n     int // how much of mem is used
+	seed  uint64
+
+	// init reports whether v1-v4 hold a seeded state. The lanes of a zero
+	// Digest are set up lazily (with a zero seed) the first time a full
+	// block is processed; until then, only v3 is read, and its zero-seed
+	// value is 0.
+	init bool
 }
 
 // New creates a new Digest with a zero seed.
@@ -57,12 +68,19 @@ func (d *Digest) Reset() {
 // ResetWithSeed clears the Digest's state so that it can be reused.
 // It uses the given seed to initialize the state.
 func (d *Digest) ResetWithSeed(seed uint64) {
-	d.v1 = seed + prime1 + prime2
-	d.v2 = seed + prime2
-	d.v3 = seed
-	d.v4 = seed - prime1
+	d.v1, d.v2, d.v3, d.v4 = initLanes(seed)
+	d.seed = seed
 	d.total = 0
 	d.n = 0
+	d.init = true
+}
+
+// Seed returns the seed that d was created or last reset with.
+func (d *Digest) Seed() uint64 { return d.seed }
+
+// initLanes returns the initial values of v1-v4 for the given seed.
+func initLanes(seed uint64) (v1, v2, v3, v4 uint64) {
+	return seed + prime1 + prime2, seed + prime2, seed, seed - prime1
 }
 
 // Size always returns 8 bytes.
@@ -75,23 +93,48 @@ func (d *Digest) BlockSize() int { return 32 }
 func (d *Digest) Write(b []byte) (n int, err error) {
 	n = len(b)
 	d.total += uint64(n)
-
-	memleft := d.mem[d.n&(len(d.mem)-1):]
-
-	if d.n+n < 32 {
-		// This new data doesn't even fill the current block.
-		copy(memleft, b)
-		d.n += n
+	off := d.n
+	if off+n >= len(d.mem) {
+		d.write(b)
 		return
 	}
+	// This new data doesn't even fill the current block. Rather than
+	// calling memmove, copy it using a couple of (possibly overlapping)
+	// word-sized moves.
+	mem := d.mem[off : off+n]
+	switch {
+	case n >= 16:
+		binary.LittleEndian.PutUint64(mem, u64(b))
+		binary.LittleEndian.PutUint64(mem[8:], u64(b[8:]))
+		binary.LittleEndian.PutUint64(mem[n-16:], u64(b[n-16:]))
+		binary.LittleEndian.PutUint64(mem[n-8:], u64(b[n-8:]))
+	case n >= 8:
+		binary.LittleEndian.PutUint64(mem, u64(b))
+		binary.LittleEndian.PutUint64(mem[n-8:], u64(b[n-8:]))
+	case n >= 4:
+		binary.LittleEndian.PutUint32(mem, u32(b))
+		binary.LittleEndian.PutUint32(mem[n-4:], u32(b[n-4:]))
+	case n > 0:
+		mem[0] = b[0]
+		mem[n/2] = b[n/2]
+		mem[n-1] = b[n-1]
+	}
+	d.n = off + n
+	return
+}
+
+// write is the slow path of Write, for writes that complete at least one
+// block.
+func (d *Digest) write(b []byte) {
+	if !d.init {
+		d.v1, d.v2, d.v3, d.v4 = initLanes(0)
+		d.init = true
+	}
 
 	if d.n > 0 {
 		// Finish off the partial block.
-		c := copy(memleft, b)
-		d.v1 = round(d.v1, u64(d.mem[0:8]))
-		d.v2 = round(d.v2, u64(d.mem[8:16]))
-		d.v3 = round(d.v3, u64(d.mem[16:24]))
-		d.v4 = round(d.v4, u64(d.mem[24:32]))
+		c := copy(d.mem[d.n&(len(d.mem)-1):], b)
+		d.writeMem()
 		b = b[c:]
 		d.n = 0
 	}
@@ -105,8 +148,148 @@ func (d *Digest) Write(b []byte) (n int, err error) {
 	// Store any remaining partial block.
 	copy(d.mem[:], b)
 	d.n = len(b)
+}
 
-	return
+// writeMem mixes the full block in d.mem into the lanes.
+func (d *Digest) writeMem() {
+	d.v1 = round(d.v1, u64(d.mem[0:8]))
+	d.v2 = round(d.v2, u64(d.mem[8:16]))
+	d.v3 = round(d.v3, u64(d.mem[16:24]))
+	d.v4 = round(d.v4, u64(d.mem[24:32]))
+}
+
+// readBufSize is the size of the buffers used by ReadFrom. It is a multiple
+// of the block size, and large enough that per-Read overhead is negligible.
+const readBufSize = 64 << 10
+
+// readBufPool holds *[readBufSize]byte buffers for ReadFrom. Buffers this
+// large are allocated on page boundaries, so the blocks that writeBlocks
+// reads from them are aligned.
+var readBufPool = sync.Pool{
+	New: func() interface{} { return new([readBufSize]byte) },
+}
+
+// ReadFrom implements io.ReaderFrom. It reads data from r until EOF or an
+// error and adds it to d, returning the number of bytes read and any error
+// other than io.EOF. Functions such as io.Copy use ReadFrom automatically.
+//
+// ReadFrom reads into a large pooled buffer and hashes whole blocks directly
+// from it, so it is faster than copying through an intermediate buffer and
+// calling Write.
+func (d *Digest) ReadFrom(r io.Reader) (n int64, err error) {
+	buf := readBufPool.Get().(*[readBufSize]byte)
+	defer readBufPool.Put(buf)
+
+	if !d.init {
+		d.v1, d.v2, d.v3, d.v4 = initLanes(0)
+		d.init = true
+	}
+	// Move any partial block to the front of buf; from here on, buf[:k]
+	// holds the data that has not been hashed yet.
+	k := copy(buf[:], d.mem[:d.n])
+	d.n = 0
+	for {
+		m, rerr := r.Read(buf[k:])
+		n += int64(m)
+		d.total += uint64(m)
+		k += m
+		if k >= 32 {
+			nw := writeBlocks(d, buf[:k])
+			k = copy(buf[:], buf[nw:k])
+		}
+		if rerr != nil {
+			if rerr != io.EOF {
+				err = rerr
+			}
+			break
+		}
+	}
+	d.n = copy(d.mem[:], buf[:k])
+	return n, err
+}
+
+// SumReader computes the 64-bit xxHash digest, with a zero seed, of the data
+// read from r until EOF. It returns the digest and any read error other than
+// io.EOF.
+func SumReader(r io.Reader) (uint64, error) {
+	var d Digest
+	_, err := d.ReadFrom(r)
+	return d.Sum64(), err
+}
+
+// The following methods write fixed-size values to d in a canonical
+// encoding that does not depend on the platform: integers and floats are
+// written in little-endian byte order, so WriteUint64(x) is equivalent to
+// writing the 8 bytes produced by binary.LittleEndian.PutUint64. Small values
+// go straight into d's internal buffer, without an intermediate slice.
+
+// WriteByte adds the single byte c to d. It always returns nil.
+// It implements io.ByteWriter.
+func (d *Digest) WriteByte(c byte) error {
+	if d.n < len(d.mem)-1 {
+		d.mem[d.n] = c
+		d.n++
+		d.total++
+		return nil
+	}
+	d.Write([]byte{c})
+	return nil
+}
+
+// WriteUint16 adds the 2-byte little-endian encoding of x to d.
+func (d *Digest) WriteUint16(x uint16) {
+	if d.n < len(d.mem)-2 {
+		binary.LittleEndian.PutUint16(d.mem[d.n:], x)
+		d.n += 2
+		d.total += 2
+		return
+	}
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], x)
+	d.Write(b[:])
+}
+
+// WriteUint32 adds the 4-byte little-endian encoding of x to d.
+func (d *Digest) WriteUint32(x uint32) {
+	if d.n < len(d.mem)-4 {
+		binary.LittleEndian.PutUint32(d.mem[d.n:], x)
+		d.n += 4
+		d.total += 4
+		return
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], x)
+	d.Write(b[:])
+}
+
+// WriteUint64 adds the 8-byte little-endian encoding of x to d.
+func (d *Digest) WriteUint64(x uint64) {
+	if d.n < len(d.mem)-8 {
+		binary.LittleEndian.PutUint64(d.mem[d.n:], x)
+		d.n += 8
+		d.total += 8
+		return
+	}
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	d.Write(b[:])
+}
+
+// WriteFloat64 adds the IEEE 754 binary representation of x to d, as
+// WriteUint64(math.Float64bits(x)). Values that compare equal but have
+// different representations, such as 0 and -0, write different bytes, and
+// so do NaNs with different payloads.
+func (d *Digest) WriteFloat64(x float64) {
+	d.WriteUint64(math.Float64bits(x))
+}
+
+// WriteStringPrefixed adds len(s), encoded as by WriteUint64, followed by
+// the bytes of s to d. Unlike plain WriteString calls, a sequence of
+// WriteStringPrefixed calls is unambiguous: writing "ab" then "c" differs
+// from writing "a" then "bc".
+func (d *Digest) WriteStringPrefixed(s string) {
+	d.WriteUint64(uint64(len(s)))
+	d.WriteString(s)
 }
 
 // Sum appends the current hash to b and returns the resulting slice.
@@ -127,93 +310,103 @@ func (d *Digest) Sum(b []byte) []byte {
 
 // Sum64 returns the current hash.
 func (d *Digest) Sum64() uint64 {
-	var h uint64
-
-	if d.total >= 32 {
-		v1, v2, v3, v4 := d.v1, d.v2, d.v3, d.v4
-		h = rol1(v1) + rol7(v2) + rol12(v3) + rol18(v4)
-		h = mergeRound(h, v1)
-		h = mergeRound(h, v2)
-		h = mergeRound(h, v3)
-		h = mergeRound(h, v4)
-	} else {
-		h = d.v3 + prime5
-	}
-
-	h += d.total
-
-	b := d.mem[:d.n&(len(d.mem)-1)]
-	for ; len(b) >= 8; b = b[8:] {
-		k1 := round(0, u64(b[:8]))
-		h ^= k1
-		h = rol27(h)*prime1 + prime4
-	}
-	if len(b) >= 4 {
-		h ^= uint64(u32(b[:4])) * prime1
-		h = rol23(h)*prime2 + prime3
-		b = b[4:]
-	}
-	for ; len(b) > 0; b = b[1:] {
-		h ^= uint64(b[0]) * prime5
-		h = rol11(h) * prime1
-	}
+	return digestSum64(d, d.mem[:d.n&(len(d.mem)-1)])
+}
 
+// Mix64 applies the XXH64 avalanche finalizer to h. It is a bijection that
+// thoroughly mixes the bits of its input, which makes it useful on its own
+// for scrambling integer keys. (It is not a hash of h: Sum64Uint64 is.)
+func Mix64(h uint64) uint64 {
 	h ^= h >> 33
 	h *= prime2
 	h ^= h >> 29
 	h *= prime3
 	h ^= h >> 32
-
 	return h
 }
 
-const (
-	magic         = "xxh\x06"
-	marshaledSize = len(magic) + 8*5 + 32
-)
+// Sum64Uint64 computes the 64-bit xxHash digest, with a zero seed, of the
+// 8-byte little-endian encoding of x. It returns the same value as Sum64 on
+// that encoding without needing a byte slice.
+func Sum64Uint64(x uint64) uint64 {
+	return Sum64Uint64WithSeed(x, 0)
+}
 
-// MarshalBinary implements the encoding.BinaryMarshaler interface.
-func (d *Digest) MarshalBinary() ([]byte, error) {
-	b := make([]byte, 0, marshaledSize)
-	b = append(b, magic...)
-	b = appendUint64(b, d.v1)
-	b = appendUint64(b, d.v2)
-	b = appendUint64(b, d.v3)
-	b = appendUint64(b, d.v4)
-	b = appendUint64(b, d.total)
-	b = append(b, d.mem[:d.n]...)
-	b = b[:len(b)+len(d.mem)-d.n]
-	return b, nil
-}
-
-// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
-func (d *Digest) UnmarshalBinary(b []byte) error {
-	if len(b) < len(magic) || string(b[:len(magic)]) != magic {
-		return errors.New("xxhash: invalid hash state identifier")
-	}
-	if len(b) != marshaledSize {
-		return errors.New("xxhash: invalid hash state size")
-	}
-	b = b[len(magic):]
-	b, d.v1 = consumeUint64(b)
-	b, d.v2 = consumeUint64(b)
-	b, d.v3 = consumeUint64(b)
-	b, d.v4 = consumeUint64(b)
-	b, d.total = consumeUint64(b)
-	copy(d.mem[:], b)
-	d.n = int(d.total % uint64(len(d.mem)))
-	return nil
+// Sum64Uint64WithSeed is like Sum64Uint64 but uses the given seed.
+func Sum64Uint64WithSeed(x, seed uint64) uint64 {
+	h := seed + prime5 + 8
+	h ^= rol31(x*prime2) * prime1 // round(0, x)
+	h = rol27(h)*prime1 + prime4
+	return Mix64(h)
+}
+
+// Sum64Uint32 computes the 64-bit xxHash digest, with a zero seed, of the
+// 4-byte little-endian encoding of x. It returns the same value as Sum64 on
+// that encoding without needing a byte slice.
+func Sum64Uint32(x uint32) uint64 {
+	return Sum64Uint32WithSeed(x, 0)
 }
 
-func appendUint64(b []byte, x uint64) []byte {
-	var a [8]byte
-	binary.LittleEndian.PutUint64(a[:], x)
-	return append(b, a[:]...)
+// Sum64Uint32WithSeed is like Sum64Uint32 but uses the given seed.
+func Sum64Uint32WithSeed(x uint32, seed uint64) uint64 {
+	h := seed + prime5 + 4
+	h ^= uint64(x) * prime1
+	h = rol23(h)*prime2 + prime3
+	return Mix64(h)
+}
+
+// Clone returns a copy of d. Writes to the copy do not affect d, and vice
+// versa. Since a Digest holds no references, a plain assignment (d2 := *d)
+// is equivalent; Clone is provided for convenience.
+func (d *Digest) Clone() *Digest {
+	c := *d
+	return &c
+}
+
+// A Template holds the state of a Digest after some common prefix has been
+// written to it. It computes hashes of that prefix followed by different
+// suffixes without rehashing the prefix.
+//
+// A Template is immutable and is safe for concurrent use.
+type Template struct {
+	d Digest
 }
 
-func consumeUint64(b []byte) ([]byte, uint64) {
-	x := u64(b)
-	return b[8:], x
+// Template returns a Template that captures d's current state. Subsequent
+// writes to d do not affect the Template.
+func (d *Digest) Template() *Template {
+	t := &Template{d: *d}
+	if !t.d.init {
+		t.d.v1, t.d.v2, t.d.v3, t.d.v4 = initLanes(0)
+		t.d.init = true
+	}
+	return t
+}
+
+// Sum64 returns the hash of the template's prefix followed by suffix.
+// It is equivalent to (but faster than) cloning the originating Digest,
+// writing suffix to it, and calling Sum64.
+func (t *Template) Sum64(suffix []byte) uint64 {
+	d := t.d
+	d.total += uint64(len(suffix))
+
+	if d.n > 0 {
+		c := copy(d.mem[d.n:], suffix)
+		if d.n+c < len(d.mem) {
+			// The suffix doesn't complete the buffered partial block.
+			return digestSum64(&d, d.mem[:d.n+c])
+		}
+		d.writeMem()
+		suffix = suffix[c:]
+	}
+
+	if len(suffix) >= 32 {
+		nw := writeBlocks(&d, suffix)
+		suffix = suffix[nw:]
+	}
+
+	// Finish directly from the suffix rather than buffering its tail.
+	return digestSum64(&d, suffix)
 }
 
 func u64(b []byte) uint64 { return binary.LittleEndian.Uint64(b) }
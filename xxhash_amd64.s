@@ -7,7 +7,7 @@
 
 // Registers:
 #define h      AX
-#define d      AX
+#define digest AX
 #define p      SI // pointer to advance through b
 #define n      DX
 #define end    BX // loop end
@@ -57,16 +57,90 @@ loop:  \
 	CMPQ p, end    \
 	JLE  loop
 
-// func Sum64(b []byte) uint64
-TEXT ·Sum64(SB), NOSPLIT|NOFRAME, $0-32
+// mergeLanes computes h from v1, v2, v3, and v4 after the block loop.
+#define mergeLanes() \
+	MOVQ v1, h        \
+	ROLQ $1, h        \
+	MOVQ v2, x        \
+	ROLQ $7, x        \
+	ADDQ x, h         \
+	MOVQ v3, x        \
+	ROLQ $12, x       \
+	ADDQ x, h         \
+	MOVQ v4, x        \
+	ROLQ $18, x       \
+	ADDQ x, h         \
+	mergeRound(h, v1) \
+	mergeRound(h, v2) \
+	mergeRound(h, v3) \
+	mergeRound(h, v4)
+
+// tail mixes the remaining (fewer than 32) bytes at p into h and finalizes
+// it. It assumes that end is p+n-32 for the original p. The caller must
+// already have added the total input length to h.
+#define tail()                    \
+	ADDQ $24, end             \
+	CMPQ p, end               \
+	JG   try4                 \
+loop8:                            \
+	MOVQ  (p), x              \
+	ADDQ  $8, p               \
+	round0(x)                 \
+	XORQ  x, h                \
+	ROLQ  $27, h              \
+	IMULQ prime1, h           \
+	ADDQ  prime4, h           \
+	CMPQ p, end               \
+	JLE  loop8                \
+try4:                             \
+	ADDQ $4, end              \
+	CMPQ p, end               \
+	JG   try1                 \
+	MOVL  (p), x              \
+	ADDQ  $4, p               \
+	IMULQ prime1, x           \
+	XORQ  x, h                \
+	ROLQ  $23, h              \
+	IMULQ prime2, h           \
+	ADDQ  ·primes+16(SB), h   \
+try1:                             \
+	ADDQ $4, end              \
+	CMPQ p, end               \
+	JGE  finalize             \
+loop1:                            \
+	MOVBQZX (p), x            \
+	ADDQ    $1, p             \
+	IMULQ   ·primes+32(SB), x \
+	XORQ    x, h              \
+	ROLQ    $11, h            \
+	IMULQ   prime1, h         \
+	CMPQ p, end               \
+	JL   loop1                \
+finalize:                         \
+	MOVQ  h, x                \
+	SHRQ  $33, x              \
+	XORQ  x, h                \
+	IMULQ prime2, h           \
+	MOVQ  h, x                \
+	SHRQ  $29, x              \
+	XORQ  x, h                \
+	IMULQ ·primes+16(SB), h   \
+	MOVQ  h, x                \
+	SHRQ  $32, x              \
+	XORQ  x, h
+
+// func sum64WithSeedAsm(b []byte, seed uint64) uint64
+TEXT ·sum64WithSeedAsm(SB), NOSPLIT|NOFRAME, $0-40
 	// Load fixed primes.
 	MOVQ ·primes+0(SB), prime1
 	MOVQ ·primes+8(SB), prime2
 	MOVQ ·primes+24(SB), prime4
 
-	// Load slice.
+	// Load slice and seed. The seed is kept in v3, which is also its
+	// initial lane value.
 	MOVQ b_base+0(FP), p
 	MOVQ b_len+8(FP), n
+	MOVQ seed+24(FP), v3
 	LEAQ (p)(n*1), end
 
 	// The first loop limit will be len(b)-32.
@@ -76,105 +150,33 @@ TEXT ·Sum64(SB), NOSPLIT|NOFRAME, $0-32
 	CMPQ n, $32
 	JLT  noBlocks
 
-	// Set up initial state (v1, v2, v3, v4).
-	MOVQ prime1, v1
+	// Set up initial state (v1, v2, v4).
+	MOVQ v3, v1
+	ADDQ prime1, v1
 	ADDQ prime2, v1
-	MOVQ prime2, v2
-	XORQ v3, v3
-	XORQ v4, v4
+	MOVQ v3, v2
+	ADDQ prime2, v2
+	MOVQ v3, v4
 	SUBQ prime1, v4
 
 	blockLoop()
-
-	MOVQ v1, h
-	ROLQ $1, h
-	MOVQ v2, x
-	ROLQ $7, x
-	ADDQ x, h
-	MOVQ v3, x
-	ROLQ $12, x
-	ADDQ x, h
-	MOVQ v4, x
-	ROLQ $18, x
-	ADDQ x, h
-
-	mergeRound(h, v1)
-	mergeRound(h, v2)
-	mergeRound(h, v3)
-	mergeRound(h, v4)
+	mergeLanes()
 
 	JMP afterBlocks
 
 noBlocks:
-	MOVQ ·primes+32(SB), h
+	MOVQ v3, h
+	ADDQ ·primes+32(SB), h
 
 afterBlocks:
 	ADDQ n, h
+	tail()
 
-	ADDQ $24, end
-	CMPQ p, end
-	JG   try4
-
-loop8:
-	MOVQ  (p), x
-	ADDQ  $8, p
-	round0(x)
-	XORQ  x, h
-	ROLQ  $27, h
-	IMULQ prime1, h
-	ADDQ  prime4, h
-
-	CMPQ p, end
-	JLE  loop8
-
-try4:
-	ADDQ $4, end
-	CMPQ p, end
-	JG   try1
-
-	MOVL  (p), x
-	ADDQ  $4, p
-	IMULQ prime1, x
-	XORQ  x, h
-
-	ROLQ  $23, h
-	IMULQ prime2, h
-	ADDQ  ·primes+16(SB), h
-
-try1:
-	ADDQ $4, end
-	CMPQ p, end
-	JGE  finalize
-
-loop1:
-	MOVBQZX (p), x
-	ADDQ    $1, p
-	IMULQ   ·primes+32(SB), x
-	XORQ    x, h
-	ROLQ    $11, h
-	IMULQ   prime1, h
-
-	CMPQ p, end
-	JL   loop1
-
-finalize:
-	MOVQ  h, x
-	SHRQ  $33, x
-	XORQ  x, h
-	IMULQ prime2, h
-	MOVQ  h, x
-	SHRQ  $29, x
-	XORQ  x, h
-	IMULQ ·primes+16(SB), h
-	MOVQ  h, x
-	SHRQ  $32, x
-	XORQ  x, h
-
-	MOVQ h, ret+24(FP)
+	MOVQ h, ret+32(FP)
 	RET
 
-// func writeBlocks(d *Digest, b []byte) int
-TEXT ·writeBlocks(SB), NOSPLIT|NOFRAME, $0-40
+// func writeBlocksAsm(d *Digest, b []byte) int
+TEXT ·writeBlocksAsm(SB), NOSPLIT|NOFRAME, $0-40
 	// Load fixed primes needed for round.
 	MOVQ ·primes+0(SB), prime1
 	MOVQ ·primes+8(SB), prime2
@@ -186,24 +188,66 @@ TEXT ·writeBlocks(SB), NOSPLIT|NOFRAME, $0-40
 	SUBQ $32, end
 
 	// Load vN from d.
-	MOVQ s+0(FP), d
-	MOVQ 0(d), v1
-	MOVQ 8(d), v2
-	MOVQ 16(d), v3
-	MOVQ 24(d), v4
+	MOVQ d+0(FP), digest
+	MOVQ 0(digest), v1
+	MOVQ 8(digest), v2
+	MOVQ 16(digest), v3
+	MOVQ 24(digest), v4
 
 	// We don't need to check the loop condition here; this function is
 	// always called with at least one block of data to process.
 	blockLoop()
 
 	// Copy vN back to d.
-	MOVQ v1, 0(d)
-	MOVQ v2, 8(d)
-	MOVQ v3, 16(d)
-	MOVQ v4, 24(d)
+	MOVQ v1, 0(digest)
+	MOVQ v2, 8(digest)
+	MOVQ v3, 16(digest)
+	MOVQ v4, 24(digest)
 
 	// The number of bytes written is p minus the old base pointer.
 	SUBQ b_base+8(FP), p
 	MOVQ p, ret+32(FP)
 
 	RET
+
+// func digestSum64Asm(d *Digest, b []byte) uint64
+TEXT ·digestSum64Asm(SB), NOSPLIT|NOFRAME, $0-40
+	// Load fixed primes.
+	MOVQ ·primes+0(SB), prime1
+	MOVQ ·primes+8(SB), prime2
+	MOVQ ·primes+24(SB), prime4
+
+	// Load the tail slice.
+	MOVQ b_base+8(FP), p
+	MOVQ b_len+16(FP), n
+	LEAQ (p)(n*1), end
+	SUBQ $32, end
+
+	// The digest pointer can't live in digest (AX) here, since that is
+	// also h.
+	MOVQ d+0(FP), CX
+
+	// Check whether d has processed at least one block (d.total >= 32).
+	CMPQ 32(CX), $32
+	JB   noBlocks
+
+	// Load vN from d.
+	MOVQ 0(CX), v1
+	MOVQ 8(CX), v2
+	MOVQ 16(CX), v3
+	MOVQ 24(CX), v4
+
+	mergeLanes()
+
+	JMP afterBlocks
+
+noBlocks:
+	MOVQ 16(CX), h
+	ADDQ ·primes+32(SB), h
+
+afterBlocks:
+	ADDQ 32(CX), h
+	tail()
+
+	MOVQ h, ret+32(FP)
+	RET
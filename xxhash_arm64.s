@@ -56,113 +56,112 @@
 	SUB     $1, nblocks     \
 	CBNZ    nblocks, loop
 
-// func Sum64(b []byte) uint64
-TEXT ·Sum64(SB), NOSPLIT|NOFRAME, $0-32
+// mergeLanes computes h from v1, v2, v3, and v4 after the block loop.
+#define mergeLanes() \
+	ROR $64-1, v1, x1  \
+	ROR $64-7, v2, x2  \
+	ADD x1, x2         \
+	ROR $64-12, v3, x3 \
+	ROR $64-18, v4, x4 \
+	ADD x3, x4         \
+	ADD x2, x4, h      \
+	mergeRound(h, v1)  \
+	mergeRound(h, v2)  \
+	mergeRound(h, v3)  \
+	mergeRound(h, v4)
+
+// tail mixes the remaining (n mod 32) bytes at p into h and finalizes it.
+// The caller must already have added the total input length to h.
+//
+// NOTE: here, sequencing the EOR after the ROR (using a rotated register) is
+// worth a small but measurable speedup for small inputs.
+#define tail() \
+	TBZ   $4, n, try8             \
+	LDP.P 16(p), (x1, x2)         \
+	round0(x1)                    \
+	ROR   $64-27, h               \
+	EOR   x1 @> 64-27, h, h       \
+	MADD  h, prime4, prime1, h    \
+	round0(x2)                    \
+	ROR   $64-27, h               \
+	EOR   x2 @> 64-27, h, h       \
+	MADD  h, prime4, prime1, h    \
+try8:                             \
+	TBZ    $3, n, try4            \
+	MOVD.P 8(p), x1               \
+	round0(x1)                    \
+	ROR    $64-27, h              \
+	EOR    x1 @> 64-27, h, h      \
+	MADD   h, prime4, prime1, h   \
+try4:                             \
+	TBZ     $2, n, try2           \
+	MOVWU.P 4(p), x2              \
+	MUL     prime1, x2            \
+	ROR     $64-23, h             \
+	EOR     x2 @> 64-23, h, h     \
+	MADD    h, prime3, prime2, h  \
+try2:                             \
+	TBZ     $1, n, try1           \
+	MOVHU.P 2(p), x3              \
+	AND     $255, x3, x1          \
+	LSR     $8, x3, x2            \
+	MUL     prime5, x1            \
+	ROR     $64-11, h             \
+	EOR     x1 @> 64-11, h, h     \
+	MUL     prime1, h             \
+	MUL     prime5, x2            \
+	ROR     $64-11, h             \
+	EOR     x2 @> 64-11, h, h     \
+	MUL     prime1, h             \
+try1:                             \
+	TBZ   $0, n, finalize         \
+	MOVBU (p), x4                 \
+	MUL   prime5, x4              \
+	ROR   $64-11, h               \
+	EOR   x4 @> 64-11, h, h       \
+	MUL   prime1, h               \
+finalize:                         \
+	EOR h >> 33, h                \
+	MUL prime2, h                 \
+	EOR h >> 29, h                \
+	MUL prime3, h                 \
+	EOR h >> 32, h
+
+// func sum64WithSeedAsm(b []byte, seed uint64) uint64
+TEXT ·sum64WithSeedAsm(SB), NOSPLIT|NOFRAME, $0-40
 	LDP b_base+0(FP), (p, n)
 
+	// The seed is kept in v3, which is also its initial lane value.
+	MOVD seed+24(FP), v3
+
 	LDP  ·primes+0(SB), (prime1, prime2)
 	LDP  ·primes+16(SB), (prime3, prime4)
 	MOVD ·primes+32(SB), prime5
 
-	CMP  $32, n
-	CSEL LT, prime5, ZR, h // if n < 32 { h = prime5 } else { h = 0 }
-	BLT  afterLoop
+	CMP $32, n
+	BLT noBlocks
 
-	ADD  prime1, prime2, v1
-	MOVD prime2, v2
-	MOVD $0, v3
-	NEG  prime1, v4
+	ADD prime1, prime2, v1
+	ADD v3, v1
+	ADD v3, prime2, v2
+	SUB prime1, v3, v4
 
 	blockLoop()
+	mergeLanes()
+	B afterLoop
 
-	ROR $64-1, v1, x1
-	ROR $64-7, v2, x2
-	ADD x1, x2
-	ROR $64-12, v3, x3
-	ROR $64-18, v4, x4
-	ADD x3, x4
-	ADD x2, x4, h
-
-	mergeRound(h, v1)
-	mergeRound(h, v2)
-	mergeRound(h, v3)
-	mergeRound(h, v4)
+noBlocks:
+	ADD v3, prime5, h
 
 afterLoop:
 	ADD n, h
+	tail()
 
-	TBZ   $4, n, try8
-	LDP.P 16(p), (x1, x2)
-
-	round0(x1)
-
-	// NOTE: here and below, sequencing the EOR after the ROR (using a
-	// rotated register) is worth a small but measurable speedup for small
-	// inputs.
-	ROR  $64-27, h
-	EOR  x1 @> 64-27, h, h
-	MADD h, prime4, prime1, h
-
-	round0(x2)
-	ROR  $64-27, h
-	EOR  x2 @> 64-27, h, h
-	MADD h, prime4, prime1, h
-
-try8:
-	TBZ    $3, n, try4
-	MOVD.P 8(p), x1
-
-	round0(x1)
-	ROR  $64-27, h
-	EOR  x1 @> 64-27, h, h
-	MADD h, prime4, prime1, h
-
-try4:
-	TBZ     $2, n, try2
-	MOVWU.P 4(p), x2
-
-	MUL  prime1, x2
-	ROR  $64-23, h
-	EOR  x2 @> 64-23, h, h
-	MADD h, prime3, prime2, h
-
-try2:
-	TBZ     $1, n, try1
-	MOVHU.P 2(p), x3
-	AND     $255, x3, x1
-	LSR     $8, x3, x2
-
-	MUL prime5, x1
-	ROR $64-11, h
-	EOR x1 @> 64-11, h, h
-	MUL prime1, h
-
-	MUL prime5, x2
-	ROR $64-11, h
-	EOR x2 @> 64-11, h, h
-	MUL prime1, h
-
-try1:
-	TBZ   $0, n, finalize
-	MOVBU (p), x4
-
-	MUL prime5, x4
-	ROR $64-11, h
-	EOR x4 @> 64-11, h, h
-	MUL prime1, h
-
-finalize:
-	EOR h >> 33, h
-	MUL prime2, h
-	EOR h >> 29, h
-	MUL prime3, h
-	EOR h >> 32, h
-
-	MOVD h, ret+24(FP)
+	MOVD h, ret+32(FP)
 	RET
 
-// func writeBlocks(d *Digest, b []byte) int
-TEXT ·writeBlocks(SB), NOSPLIT|NOFRAME, $0-40
+// func writeBlocksAsm(d *Digest, b []byte) int
+TEXT ·writeBlocksAsm(SB), NOSPLIT|NOFRAME, $0-40
 	LDP ·primes+0(SB), (prime1, prime2)
 
 	// Load state. Assume v[1-4] are stored contiguously.
@@ -181,3 +180,36 @@ TEXT ·writeBlocks(SB), NOSPLIT|NOFRAME, $0-40
 	BIC  $31, n
 	MOVD n, ret+32(FP)
 	RET
+
+// func digestSum64Asm(d *Digest, b []byte) uint64
+TEXT ·digestSum64Asm(SB), NOSPLIT|NOFRAME, $0-40
+	MOVD d+0(FP), digest
+	LDP  b_base+8(FP), (p, n)
+
+	LDP  ·primes+0(SB), (prime1, prime2)
+	LDP  ·primes+16(SB), (prime3, prime4)
+	MOVD ·primes+32(SB), prime5
+
+	// Check whether d has processed at least one block (d.total >= 32).
+	MOVD 32(digest), x1
+	CMP  $32, x1
+	BLO  noBlocks
+
+	// Load state. Assume v[1-4] are stored contiguously.
+	LDP 0(digest), (v1, v2)
+	LDP 16(digest), (v3, v4)
+
+	mergeLanes()
+	B afterLoop
+
+noBlocks:
+	MOVD 16(digest), v3
+	ADD  v3, prime5, h
+
+afterLoop:
+	MOVD 32(digest), x1
+	ADD  x1, h
+	tail()
+
+	MOVD h, ret+32(FP)
+	RET
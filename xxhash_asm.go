@@ -1,15 +1,70 @@
-//go:build (amd64 || arm64) && !appengine && gc && !purego
-// +build amd64 arm64
+//go:build (amd64 || arm64 || ppc64le || riscv64 || s390x) && !appengine && gc && !purego
+// +build amd64 arm64 ppc64le riscv64 s390x
 // +build !appengine
 // +build gc
 // +build !purego
 
 package xxhash
 
+// hasAsm reports whether this build includes the assembly implementation.
+const hasAsm = true
+
 // Sum64 computes the 64-bit xxHash digest of b with a zero seed.
-//
+func Sum64(b []byte) uint64 {
+	return Sum64WithSeed(b, 0)
+}
+
+// Sum64WithSeed computes the 64-bit xxHash digest of b using the given seed.
+func Sum64WithSeed(b []byte, seed uint64) uint64 {
+	if len(b) >= 32 {
+		if useGeneric {
+			return sum64WithSeedGeneric(b, seed)
+		}
+		return sum64WithSeedAsm(b, seed)
+	}
+	// Inputs shorter than a block are hashed here rather than by
+	// sum64WithSeedGeneric or the assembly, either of which would cost
+	// another call. This is the tail of sum64WithSeedGeneric.
+	h := seed + prime5 + uint64(len(b))
+	for ; len(b) >= 8; b = b[8:] {
+		k1 := round(0, u64(b[:8]))
+		h ^= k1
+		h = rol27(h)*prime1 + prime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(u32(b[:4])) * prime1
+		h = rol23(h)*prime2 + prime3
+		b = b[4:]
+	}
+	for ; len(b) > 0; b = b[1:] {
+		h ^= uint64(b[0]) * prime5
+		h = rol11(h) * prime1
+	}
+	return Mix64(h)
+}
+
+// digestSum64 computes the hash of d's state followed by the trailing
+// partial block b, which must be shorter than 32 bytes. d.total must
+// already include len(b).
+func digestSum64(d *Digest, b []byte) uint64 {
+	if useGeneric {
+		return digestSum64Generic(d, b)
+	}
+	return digestSum64Asm(d, b)
+}
+
+func writeBlocks(d *Digest, b []byte) int {
+	if useGeneric {
+		return writeBlocksGeneric(d, b)
+	}
+	return writeBlocksAsm(d, b)
+}
+
+//go:noescape
+func sum64WithSeedAsm(b []byte, seed uint64) uint64
+
 //go:noescape
-func Sum64(b []byte) uint64
+func writeBlocksAsm(d *Digest, b []byte) int
 
 //go:noescape
-func writeBlocks(d *Digest, b []byte) int
+func digestSum64Asm(d *Digest, b []byte) uint64
@@ -0,0 +1,24 @@
+package xxhash
+
+// Sum64Batch computes the 64-bit xxHash digest (with a zero seed) of each
+// key, storing the digest of keys[i] in out[i]. It panics if out is shorter
+// than keys.
+//
+// Sum64Batch produces the same results as calling Sum64 on each key. It is
+// faster for large batches of short keys (under 32 bytes): on amd64
+// processors with AVX2 or AVX-512 support, several keys are hashed in
+// parallel using vector instructions.
+func Sum64Batch(keys [][]byte, out []uint64) {
+	out = out[:len(keys)]
+	for i := sum64Batch(keys, out); i < len(keys); i++ {
+		out[i] = Sum64(keys[i])
+	}
+}
+
+// Sum64StringBatch is like Sum64Batch but for string keys.
+func Sum64StringBatch(keys []string, out []uint64) {
+	out = out[:len(keys)]
+	for i := sum64StringBatch(keys, out); i < len(keys); i++ {
+		out[i] = Sum64String(keys[i])
+	}
+}
@@ -0,0 +1,113 @@
+//go:build !appengine && gc && !purego
+// +build !appengine,gc,!purego
+
+package xxhash
+
+import "unsafe"
+
+// The vectorized batch kernels hash n keys (n must be a positive multiple of
+// the lane count: 4 for AVX2, 8 for AVX-512). Each key is described by a
+// header at keys+i*stride holding its data pointer followed by its length,
+// which matches the layout of both []byte and string. The result for key i
+// is stored in out[i].
+//
+// The kernels only implement the short-input (< 32 bytes) path of XXH64.
+// They report whether any key was 32 bytes or longer; the results for such
+// keys are meaningless and must be recomputed by the caller.
+
+//go:noescape
+func sum64BatchAVX2(keys unsafe.Pointer, n, stride int, out *uint64) bool
+
+//go:noescape
+func sum64BatchAVX512(keys unsafe.Pointer, n, stride int, out *uint64) bool
+
+// hasAVX2 and hasAVX512 report whether the CPU supports each kernel;
+// useAVX2 and useAVX512 report whether Sum64Batch uses it (see
+// SetImplementation).
+var (
+	hasAVX2, hasAVX512 = detectAVX()
+	useAVX2, useAVX512 = hasAVX2, hasAVX512
+)
+
+func detectAVX() (avx2, avx512 bool) {
+	if maxID, _, _, _ := cpuid(0, 0); maxID < 7 {
+		return false, false
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	const (
+		osxsave = 1 << 27
+		avx     = 1 << 28
+	)
+	if ecx1&(osxsave|avx) != osxsave|avx {
+		return false, false
+	}
+	// Check that the OS saves the YMM (and, for AVX-512, the opmask and
+	// ZMM) register state.
+	xcr0, _ := xgetbv()
+	if xcr0&0x6 != 0x6 {
+		return false, false
+	}
+	_, ebx7, _, _ := cpuid(7, 0)
+	const (
+		avx2Bit     = 1 << 5
+		avx512FBit  = 1 << 16
+		avx512DQBit = 1 << 17
+	)
+	avx2 = ebx7&avx2Bit != 0
+	avx512 = avx2 && ebx7&(avx512FBit|avx512DQBit) == avx512FBit|avx512DQBit &&
+		xcr0&0xe6 == 0xe6
+	return avx2, avx512
+}
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+func xgetbv() (eax, edx uint32)
+
+func sum64Batch(keys [][]byte, out []uint64) int {
+	if len(keys) == 0 {
+		return 0
+	}
+	n, long := batchKernel(unsafe.Pointer(&keys[0]), len(keys), int(unsafe.Sizeof(keys[0])), out)
+	if long {
+		for i, k := range keys[:n] {
+			if len(k) >= 32 {
+				out[i] = Sum64(k)
+			}
+		}
+	}
+	return n
+}
+
+func sum64StringBatch(keys []string, out []uint64) int {
+	if len(keys) == 0 {
+		return 0
+	}
+	n, long := batchKernel(unsafe.Pointer(&keys[0]), len(keys), int(unsafe.Sizeof(keys[0])), out)
+	if long {
+		for i, k := range keys[:n] {
+			if len(k) >= 32 {
+				out[i] = Sum64String(k)
+			}
+		}
+	}
+	return n
+}
+
+// batchKernel hashes as many of the n keys whose headers start at keys as
+// the available kernels can handle, using the widest kernel first. It
+// returns the number of keys hashed and whether any of them was too long
+// for the kernels (see above).
+func batchKernel(keys unsafe.Pointer, n, stride int, out []uint64) (done int, long bool) {
+	if useAVX512 && n >= 8 {
+		done = n &^ 7
+		long = sum64BatchAVX512(keys, done, stride, &out[0])
+	}
+	if useAVX2 && n-done >= 4 {
+		m := (n - done) &^ 3
+		if sum64BatchAVX2(unsafe.Pointer(uintptr(keys)+uintptr(done*stride)), m, stride, &out[done]) {
+			long = true
+		}
+		done += m
+	}
+	return done, long
+}
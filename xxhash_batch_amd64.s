@@ -0,0 +1,454 @@
+//go:build !appengine && gc && !purego
+// +build !appengine
+// +build gc
+// +build !purego
+
+#include "textflag.h"
+
+// Vector constants for the batch kernels. Each entry is a 32-byte vector
+// holding four copies of the value; the AVX-512 kernel broadcasts the first
+// copy.
+#define VCONST(off, v) \
+	DATA batchConsts<>+(off+0)(SB)/8, v  \
+	DATA batchConsts<>+(off+8)(SB)/8, v  \
+	DATA batchConsts<>+(off+16)(SB)/8, v \
+	DATA batchConsts<>+(off+24)(SB)/8, v
+
+VCONST(0, $11400714785074694791)   // prime1
+VCONST(32, $0x9e3779b1)            // prime1 >> 32
+VCONST(64, $14029467366897019727)  // prime2
+VCONST(96, $0xc2b2ae3d)            // prime2 >> 32
+VCONST(128, $1609587929392839161)  // prime3
+VCONST(160, $0x165667b1)           // prime3 >> 32
+VCONST(192, $9650029242287828579)  // prime4
+VCONST(224, $2870177450012600261)  // prime5
+VCONST(256, $0x27d4eb2f)           // prime5 >> 32
+VCONST(288, $3)
+VCONST(320, $7)
+VCONST(352, $8)
+VCONST(384, $15)
+VCONST(416, $23)
+VCONST(448, $31)
+VCONST(480, $32)
+VCONST(512, $255)
+VCONST(544, $4088)
+VCONST(576, $4095)
+VCONST(608, $1)
+VCONST(640, $2)
+GLOBL batchConsts<>(SB), RODATA|NOPTR, $672
+
+#define cPrime1   batchConsts<>+0(SB)
+#define cPrime1Hi batchConsts<>+32(SB)
+#define cPrime2   batchConsts<>+64(SB)
+#define cPrime2Hi batchConsts<>+96(SB)
+#define cPrime3   batchConsts<>+128(SB)
+#define cPrime3Hi batchConsts<>+160(SB)
+#define cPrime4   batchConsts<>+192(SB)
+#define cPrime5   batchConsts<>+224(SB)
+#define cPrime5Hi batchConsts<>+256(SB)
+#define c3        batchConsts<>+288(SB)
+#define c7        batchConsts<>+320(SB)
+#define c8        batchConsts<>+352(SB)
+#define c15       batchConsts<>+384(SB)
+#define c23       batchConsts<>+416(SB)
+#define c31       batchConsts<>+448(SB)
+#define c32       batchConsts<>+480(SB)
+#define c255      batchConsts<>+512(SB)
+#define c4088     batchConsts<>+544(SB)
+#define c4095     batchConsts<>+576(SB)
+#define c1        batchConsts<>+608(SB)
+#define c2        batchConsts<>+640(SB)
+
+// Lane indexes 0-7, used to compute the offsets of the key headers.
+DATA batchIota<>+0(SB)/8, $0
+DATA batchIota<>+8(SB)/8, $1
+DATA batchIota<>+16(SB)/8, $2
+DATA batchIota<>+24(SB)/8, $3
+DATA batchIota<>+32(SB)/8, $4
+DATA batchIota<>+40(SB)/8, $5
+DATA batchIota<>+48(SB)/8, $6
+DATA batchIota<>+56(SB)/8, $7
+GLOBL batchIota<>(SB), RODATA|NOPTR, $64
+
+// Both kernels hash one group of keys at a time, one key per 64-bit lane,
+// following the short-input path of XXH64:
+//
+//	h := prime5 + len
+//	for each 8-byte word w: h = rol27(h^round(0, w))*prime1 + prime4
+//	for a 4-byte word w:    h = rol23(h^(w*prime1))*prime2 + prime3
+//	for each byte c:        h = rol11(h^(c*prime5))*prime1
+//	avalanche(h)
+//
+// Each step is computed for every lane and merged into h only for the lanes
+// that still have enough input left. The 8-byte words are loaded with masked
+// gathers, so no lane reads past the end of its key. The final partial word
+// (up to 7 bytes) of each key is loaded with a single 8-byte gather. If that
+// load would cross a page boundary, it is instead done from 8 bytes before
+// the end of the key; either way the load stays within pages that contain
+// key bytes, and the extra bytes are shifted out.
+
+// Registers shared by both kernels:
+#define hdrs  AX // key headers
+#define count BX
+#define step  CX // header stride
+#define outp  DI
+#define zero   R8 // zero base register for gathers of absolute addresses
+
+// AVX2 registers:
+#define yH     Y0  // hash state
+#define yP     Y1  // key data pointers
+#define yL     Y2  // key lengths
+#define yX     Y3  // input word / scratch
+#define yMask  Y4
+#define yT1    Y5
+#define yT2    Y6
+#define yHn    Y7  // candidate hash state for the current step
+#define yRem   Y8  // length of the final partial word
+#define yLong  Y9  // lanes with keys of 32 bytes or more
+#define yIdx   Y10 // offsets of the key headers
+#define yZero  Y11
+#define yTail  Y12 // final partial word; saved gather mask
+
+// mulAVX2 sets a = a*c (mod 2^64), where clo holds c and chi holds c>>32 in
+// each lane. AVX2 only has a 32x32->64-bit multiply, so this is assembled
+// from three partial products.
+#define mulAVX2(a, clo, chi) \
+	VPSRLQ   $32, a, yT1  \
+	VPMULUDQ clo, yT1, yT1 \
+	VPMULUDQ chi, a, yT2   \
+	VPADDQ   yT1, yT2, yT1 \
+	VPSLLQ   $32, yT1, yT1 \
+	VPMULUDQ clo, a, a     \
+	VPADDQ   yT1, a, a
+
+// mul32AVX2 sets dst = uint32(a)*c (mod 2^64).
+#define mul32AVX2(a, dst, clo, chi) \
+	VPMULUDQ chi, a, yT1 \
+	VPSLLQ   $32, yT1, yT1 \
+	VPMULUDQ clo, a, dst   \
+	VPADDQ   yT1, dst, dst
+
+#define rolAVX2(k, a) \
+	VPSRLQ $(64-k), a, yT1 \
+	VPSLLQ $k, a, a        \
+	VPOR   yT1, a, a
+
+// wordAVX2 loads the 8-byte word at offset off of each key whose length is
+// greater than limit and mixes it into yH.
+#define wordAVX2(off, limit) \
+	VPCMPGTQ   limit, yL, yMask             \
+	VPTEST     yMask, yMask                 \
+	JZ         tailAVX2                     \
+	VMOVDQA    yMask, yTail                 \
+	VPGATHERQQ yMask, off(zero)(yP*1), yX   \
+	mulAVX2(yX, cPrime2, cPrime2Hi)         \
+	rolAVX2(31, yX)                         \
+	mulAVX2(yX, cPrime1, cPrime1Hi)         \
+	VPXOR      yX, yH, yHn                  \
+	rolAVX2(27, yHn)                        \
+	mulAVX2(yHn, cPrime1, cPrime1Hi)        \
+	VPADDQ     cPrime4, yHn, yHn            \
+	VPBLENDVB  yTail, yHn, yH, yH
+
+// byteAVX2 mixes the low byte of yTail into yH for each lane whose
+// remaining byte count is greater than limit.
+#define byteAVX2(limit) \
+	VPCMPGTQ  limit, yRem, yMask               \
+	VPAND     c255, yTail, yX                  \
+	mul32AVX2(yX, yHn, cPrime5, cPrime5Hi)     \
+	VPXOR     yH, yHn, yHn                     \
+	rolAVX2(11, yHn)                           \
+	mulAVX2(yHn, cPrime1, cPrime1Hi)           \
+	VPBLENDVB yMask, yHn, yH, yH               \
+	VPSRLQ    $8, yTail, yTail
+
+// func sum64BatchAVX2(keys unsafe.Pointer, n, stride int, out *uint64) bool
+TEXT ·sum64BatchAVX2(SB), NOSPLIT, $0-33
+	MOVQ keys+0(FP), hdrs
+	MOVQ n+8(FP), count
+	MOVQ stride+16(FP), step
+	MOVQ out+24(FP), outp
+	XORQ zero, zero
+
+	VPBROADCASTQ stride+16(FP), yIdx
+	VPMULUDQ     batchIota<>(SB), yIdx, yIdx
+	SHLQ         $2, step // advance 4 headers per group
+	VPXOR        yLong, yLong, yLong
+	VPXOR        yZero, yZero, yZero
+
+loopAVX2:
+	// Load the data pointers and lengths from the key headers.
+	VPCMPEQQ   yMask, yMask, yMask
+	VPGATHERQQ yMask, (hdrs)(yIdx*1), yP
+	VPCMPEQQ   yMask, yMask, yMask
+	VPGATHERQQ yMask, 8(hdrs)(yIdx*1), yL
+
+	// Skip groups made up entirely of long keys; they are rehashed by the
+	// caller anyway.
+	VPCMPGTQ  c31, yL, yMask
+	VPOR      yMask, yLong, yLong
+	VMOVMSKPD yMask, R9
+	CMPQ      R9, $0xf
+	JEQ       nextAVX2
+
+	VPADDQ cPrime5, yL, yH
+
+	wordAVX2(0, c7)
+	wordAVX2(8, c15)
+	wordAVX2(16, c23)
+
+tailAVX2:
+	// yRem = len & 7; yX = address of the final partial word.
+	VPAND  c7, yL, yRem
+	VPADDQ yL, yP, yX
+	VPSUBQ yRem, yX, yX
+
+	VPCMPGTQ yZero, yRem, yMask
+	VPTEST   yMask, yMask
+	JZ       finalizeAVX2
+
+	// If the partial word is in the last 8 bytes of a page, load the 8
+	// bytes ending at the end of the key instead.
+	VPAND    c4095, yX, yT1
+	VPCMPGTQ c4088, yT1, yT1
+	VPSUBQ   c8, yRem, yT2
+	VPAND    yT1, yT2, yT2
+	VPADDQ   yT2, yX, yX
+
+	VPXOR      yTail, yTail, yTail
+	VPGATHERQQ yMask, (zero)(yX*1), yTail
+
+	// Shift out the bytes that don't belong to the partial word: for a
+	// forward load they are at the top (shift left, then right); for a
+	// backward load they are at the bottom (shift right only).
+	VMOVDQU c8, yT2
+	VPSUBQ  yRem, yT2, yT2
+	VPSLLQ  $3, yT2, yT2
+	VPANDN  yT2, yT1, yT1
+	VPSLLVQ yT1, yTail, yTail
+	VPSRLVQ yT2, yTail, yTail
+
+	// 4-byte word.
+	VPCMPGTQ  c3, yRem, yMask
+	mul32AVX2(yTail, yHn, cPrime1, cPrime1Hi)
+	VPXOR     yH, yHn, yHn
+	rolAVX2(23, yHn)
+	mulAVX2(yHn, cPrime2, cPrime2Hi)
+	VPADDQ    cPrime3, yHn, yHn
+	VPBLENDVB yMask, yHn, yH, yH
+	VPAND     c32, yMask, yT1
+	VPSRLVQ   yT1, yTail, yTail
+	VPAND     c3, yRem, yRem
+
+	byteAVX2(yZero)
+	byteAVX2(c1)
+	byteAVX2(c2)
+
+finalizeAVX2:
+	VPSRLQ $33, yH, yT2
+	VPXOR  yT2, yH, yH
+	mulAVX2(yH, cPrime2, cPrime2Hi)
+	VPSRLQ $29, yH, yT2
+	VPXOR  yT2, yH, yH
+	mulAVX2(yH, cPrime3, cPrime3Hi)
+	VPSRLQ $32, yH, yT2
+	VPXOR  yT2, yH, yH
+
+	VMOVDQU yH, (outp)
+
+nextAVX2:
+	ADDQ $32, outp
+	ADDQ step, hdrs
+	SUBQ $4, count
+	JNZ  loopAVX2
+
+	VPTEST     yLong, yLong
+	SETNE      ret+32(FP)
+	VZEROUPPER
+	RET
+
+// AVX-512 registers:
+#define zH      Z0
+#define zP      Z1
+#define zL      Z2
+#define zX      Z3
+#define zT      Z4
+#define zHn     Z7
+#define zRem    Z8
+#define zIdx    Z10
+#define zTail   Z12
+#define zPrime1 Z16
+#define zPrime2 Z17
+#define zPrime3 Z18
+#define zPrime4 Z19
+#define zPrime5 Z20
+#define z3      Z21
+#define z7      Z22
+#define z8      Z23
+#define z15     Z24
+#define z23     Z25
+#define z31     Z26
+#define z255    Z27
+#define z4088   Z28
+#define z4095   Z29
+#define z1      Z30
+#define z2      Z31
+
+// wordAVX512 is the AVX-512 version of wordAVX2.
+#define wordAVX512(off, limit) \
+	VPCMPGTQ   limit, zL, K2              \
+	KORTESTB   K2, K2                     \
+	JZ         tailAVX512                 \
+	KMOVB      K2, K1                     \
+	VPGATHERQQ off(zero)(zP*1), K1, zX    \
+	VPMULLQ    zPrime2, zX, zX            \
+	VPROLQ     $31, zX, zX                \
+	VPMULLQ    zPrime1, zX, zX            \
+	VPXORQ     zX, zH, zHn                \
+	VPROLQ     $27, zHn, zHn              \
+	VPMULLQ    zPrime1, zHn, zHn          \
+	VPADDQ     zPrime4, zHn, zHn          \
+	VMOVDQA64  zHn, K2, zH
+
+// byteAVX512 is the AVX-512 version of byteAVX2.
+#define byteAVX512(limit) \
+	VPCMPGTQ  limit, zRem, K2   \
+	VPANDQ    z255, zTail, zX   \
+	VPMULLQ   zPrime5, zX, zHn  \
+	VPXORQ    zH, zHn, zHn      \
+	VPROLQ    $11, zHn, zHn     \
+	VPMULLQ   zPrime1, zHn, zHn \
+	VMOVDQA64 zHn, K2, zH       \
+	VPSRLQ    $8, zTail, zTail
+
+// func sum64BatchAVX512(keys unsafe.Pointer, n, stride int, out *uint64) bool
+TEXT ·sum64BatchAVX512(SB), NOSPLIT, $0-33
+	MOVQ keys+0(FP), hdrs
+	MOVQ n+8(FP), count
+	MOVQ stride+16(FP), step
+	MOVQ out+24(FP), outp
+	XORQ zero, zero
+
+	VPBROADCASTQ cPrime1, zPrime1
+	VPBROADCASTQ cPrime2, zPrime2
+	VPBROADCASTQ cPrime3, zPrime3
+	VPBROADCASTQ cPrime4, zPrime4
+	VPBROADCASTQ cPrime5, zPrime5
+	VPBROADCASTQ c3, z3
+	VPBROADCASTQ c7, z7
+	VPBROADCASTQ c8, z8
+	VPBROADCASTQ c15, z15
+	VPBROADCASTQ c23, z23
+	VPBROADCASTQ c31, z31
+	VPBROADCASTQ c255, z255
+	VPBROADCASTQ c4088, z4088
+	VPBROADCASTQ c4095, z4095
+	VPBROADCASTQ c1, z1
+	VPBROADCASTQ c2, z2
+
+	VPBROADCASTQ stride+16(FP), zIdx
+	VPMULLQ      batchIota<>(SB), zIdx, zIdx
+	SHLQ         $3, step // advance 8 headers per group
+	KXORB        K3, K3, K3 // lanes with keys of 32 bytes or more
+
+loopAVX512:
+	KXNORB     K1, K1, K1
+	VPGATHERQQ (hdrs)(zIdx*1), K1, zP
+	KXNORB     K1, K1, K1
+	VPGATHERQQ 8(hdrs)(zIdx*1), K1, zL
+
+	VPCMPGTQ z31, zL, K2
+	KORB     K2, K3, K3
+	KMOVB    K2, R9
+	CMPQ     R9, $0xff
+	JEQ      nextAVX512
+
+	VPADDQ zPrime5, zL, zH
+
+	wordAVX512(0, z7)
+	wordAVX512(8, z15)
+	wordAVX512(16, z23)
+
+tailAVX512:
+	VPANDQ z7, zL, zRem
+	VPADDQ zL, zP, zX
+	VPSUBQ zRem, zX, zX
+
+	VPTESTMQ zRem, zRem, K1
+	KORTESTB K1, K1
+	JZ       finalizeAVX512
+
+	// K4 = lanes whose partial word is in the last 8 bytes of a page.
+	VPANDQ   z4095, zX, zT
+	VPCMPGTQ z4088, zT, K4
+	VPSUBQ   z8, zRem, zT
+	VPADDQ   zT, zX, K4, zX
+
+	VPXORQ     zTail, zTail, zTail
+	VPGATHERQQ (zero)(zX*1), K1, zTail
+
+	VPSUBQ  zRem, z8, zT
+	VPSLLQ  $3, zT, zT
+	KNOTB   K4, K5
+	VPSLLVQ zT, zTail, K5, zTail
+	VPSRLVQ zT, zTail, zTail
+
+	// 4-byte word.
+	VPCMPGTQ  z3, zRem, K2
+	VPSLLQ    $32, zTail, zX
+	VPSRLQ    $32, zX, zX
+	VPMULLQ   zPrime1, zX, zHn
+	VPXORQ    zH, zHn, zHn
+	VPROLQ    $23, zHn, zHn
+	VPMULLQ   zPrime2, zHn, zHn
+	VPADDQ    zPrime3, zHn, zHn
+	VMOVDQA64 zHn, K2, zH
+	VPSRLQ    $32, zTail, K2, zTail
+	VPANDQ    z3, zRem, zRem
+
+	VPXORQ zT, zT, zT
+	byteAVX512(zT)
+	byteAVX512(z1)
+	byteAVX512(z2)
+
+finalizeAVX512:
+	VPSRLQ  $33, zH, zT
+	VPXORQ  zT, zH, zH
+	VPMULLQ zPrime2, zH, zH
+	VPSRLQ  $29, zH, zT
+	VPXORQ  zT, zH, zH
+	VPMULLQ zPrime3, zH, zH
+	VPSRLQ  $32, zH, zT
+	VPXORQ  zT, zH, zH
+
+	VMOVDQU64 zH, (outp)
+
+nextAVX512:
+	ADDQ $64, outp
+	ADDQ step, hdrs
+	SUBQ $8, count
+	JNZ  loopAVX512
+
+	KORTESTB   K3, K3
+	SETNE      ret+32(FP)
+	VZEROUPPER
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
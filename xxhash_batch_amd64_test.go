@@ -0,0 +1,34 @@
+//go:build !appengine && gc && !purego
+// +build !appengine,gc,!purego
+
+package xxhash
+
+import "testing"
+
+// TestSum64BatchKernels reruns the batch tests with each vectorized kernel
+// (that the CPU supports) as the widest one available.
+func TestSum64BatchKernels(t *testing.T) {
+	defer func(avx2, avx512 bool) {
+		useAVX2, useAVX512 = avx2, avx512
+	}(useAVX2, useAVX512)
+
+	hasAVX2, hasAVX512 := useAVX2, useAVX512
+	for _, tt := range []struct {
+		name   string
+		enable bool
+		avx2   bool
+		avx512 bool
+	}{
+		{"scalar", true, false, false},
+		{"AVX2", hasAVX2, true, false},
+		{"AVX-512", hasAVX512, hasAVX2, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.enable {
+				t.Skip("not supported by this CPU")
+			}
+			useAVX2, useAVX512 = tt.avx2, tt.avx512
+			testSum64BatchAll(t)
+		})
+	}
+}
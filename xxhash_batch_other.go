@@ -0,0 +1,15 @@
+//go:build !amd64 || appengine || !gc || purego
+// +build !amd64 appengine !gc purego
+
+package xxhash
+
+// There are no vectorized batch kernels on this platform.
+const hasAVX2, hasAVX512 = false, false
+
+var useAVX2, useAVX512 bool
+
+// sum64Batch hashes a prefix of keys into out and returns the number of keys
+// it handled. Without a vectorized implementation, it handles none of them.
+func sum64Batch(keys [][]byte, out []uint64) int { return 0 }
+
+func sum64StringBatch(keys []string, out []uint64) int { return 0 }
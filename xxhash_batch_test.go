@@ -0,0 +1,134 @@
+package xxhash
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSum64Batch(t *testing.T) {
+	testSum64BatchCases(t)
+}
+
+func testSum64BatchCases(t *testing.T) {
+	input := make([]byte, 200)
+	for i := range input {
+		input[i] = byte(i*7 + 3)
+	}
+	// Mix short and long keys, with batch sizes that leave remainders for
+	// every vector width.
+	for _, numKeys := range []int{0, 1, 3, 4, 7, 8, 9, 15, 16, 17, 100} {
+		for _, maxLen := range []int{0, 8, 31, 32, 70} {
+			keys := make([][]byte, numKeys)
+			strs := make([]string, numKeys)
+			for i := range keys {
+				n := 0
+				if maxLen > 0 {
+					n = (i*13 + numKeys) % (maxLen + 1)
+				}
+				keys[i] = input[i%50 : i%50+n]
+				strs[i] = string(keys[i])
+			}
+			t.Run(fmt.Sprintf("keys=%d,maxLen=%d", numKeys, maxLen), func(t *testing.T) {
+				testSum64Batch(t, keys, strs)
+			})
+		}
+	}
+}
+
+func TestSum64BatchAllLengths(t *testing.T) {
+	testSum64BatchAllLengths(t)
+}
+
+func testSum64BatchAllLengths(t *testing.T) {
+	input := make([]byte, 64)
+	for i := range input {
+		input[i] = byte(i*11 + 5)
+	}
+	var keys [][]byte
+	var strs []string
+	for n := 0; n <= len(input); n++ {
+		for off := 0; off < 8; off++ {
+			if off+n > len(input) {
+				break
+			}
+			keys = append(keys, input[off:off+n])
+			strs = append(strs, string(input[off:off+n]))
+		}
+	}
+	testSum64Batch(t, keys, strs)
+}
+
+// TestSum64BatchPageBoundary checks keys that end at, or just before, the end
+// of a page, which exercises the vectorized kernels' tail loads.
+func TestSum64BatchPageBoundary(t *testing.T) {
+	testSum64BatchPageBoundary(t)
+}
+
+func testSum64BatchPageBoundary(t *testing.T) {
+	const pageSize = 4096
+	buf := make([]byte, 3*pageSize)
+	for i := range buf {
+		buf[i] = byte(i*5 + 1)
+	}
+	// Find the start of a page within buf.
+	page := pageSize - int(reflect.ValueOf(buf).Pointer()%pageSize)
+	if page == pageSize {
+		page = 0
+	}
+	page += pageSize
+	var keys [][]byte
+	var strs []string
+	for n := 0; n < 32; n++ {
+		for gap := 0; gap < 8; gap++ {
+			end := page - gap
+			keys = append(keys, buf[end-n:end])
+			strs = append(strs, string(buf[end-n:end]))
+			keys = append(keys, buf[page+gap:page+gap+n])
+			strs = append(strs, string(buf[page+gap:page+gap+n]))
+		}
+	}
+	testSum64Batch(t, keys, strs)
+}
+
+func testSum64Batch(t *testing.T, keys [][]byte, strs []string) {
+	t.Helper()
+	out := make([]uint64, len(keys)+1)
+	const sentinel = 0x0123456789abcdef
+	out[len(keys)] = sentinel
+	Sum64Batch(keys, out)
+	for i, k := range keys {
+		if want := Sum64(k); out[i] != want {
+			t.Fatalf("Sum64Batch: key %d (len=%d): got 0x%x; want 0x%x", i, len(k), out[i], want)
+		}
+	}
+	if out[len(keys)] != sentinel {
+		t.Fatal("Sum64Batch wrote past the end of the keys")
+	}
+	for i := range out[:len(keys)] {
+		out[i] = 0
+	}
+	Sum64StringBatch(strs, out)
+	for i, s := range strs {
+		if want := Sum64String(s); out[i] != want {
+			t.Fatalf("Sum64StringBatch: key %d (len=%d): got 0x%x; want 0x%x", i, len(s), out[i], want)
+		}
+	}
+}
+
+func TestSum64BatchShortOut(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Sum64Batch with a short out slice did not panic")
+		}
+	}()
+	Sum64Batch(make([][]byte, 3), make([]uint64, 2))
+}
+
+// testSum64BatchAll runs all of the batch tests with the current
+// implementation.
+func testSum64BatchAll(t *testing.T) {
+	testSum64BatchCases(t)
+	testSum64BatchAllLengths(t)
+	testSum64BatchPageBoundary(t)
+}
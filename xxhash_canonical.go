@@ -0,0 +1,128 @@
+package xxhash
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Hash is a 64-bit XXH64 hash value. It provides a single representation for
+// storing hashes: as text, it is 16 lowercase hexadecimal digits (the format
+// printed by xxhsum); as bytes, it is the 8-byte big-endian canonical form
+// that Digest.Sum appends.
+//
+// Hash implements encoding.TextMarshaler, so it is encoded as a hex string by
+// encoding/json and similar packages, and it implements sql.Scanner and
+// driver.Valuer for use with database/sql.
+type Hash uint64
+
+// Bytes returns the canonical representation of h: its big-endian bytes.
+// This matches XXH64_canonicalFromHash in the reference implementation.
+func (h Hash) Bytes() [8]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(h))
+	return b
+}
+
+// HashFromBytes returns the Hash whose canonical representation is b.
+// This matches XXH64_hashFromCanonical in the reference implementation.
+func HashFromBytes(b [8]byte) Hash {
+	return Hash(binary.BigEndian.Uint64(b[:]))
+}
+
+// AppendBinary appends the canonical representation of h (see Bytes) to b
+// and returns the resulting slice. It never returns an error.
+func (h Hash) AppendBinary(b []byte) ([]byte, error) {
+	c := h.Bytes()
+	return append(b, c[:]...), nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface using the
+// canonical representation of h.
+func (h Hash) MarshalBinary() ([]byte, error) {
+	return h.AppendBinary(make([]byte, 0, 8))
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// It accepts the canonical representation produced by MarshalBinary.
+func (h *Hash) UnmarshalBinary(b []byte) error {
+	if len(b) != 8 {
+		return errors.New("xxhash: invalid Hash length")
+	}
+	*h = Hash(binary.BigEndian.Uint64(b))
+	return nil
+}
+
+// String returns h as 16 lowercase hexadecimal digits, the same as
+// fmt.Sprintf("%016x", uint64(h)).
+func (h Hash) String() string {
+	b, _ := h.AppendText(make([]byte, 0, 16))
+	return string(b)
+}
+
+// AppendText appends the text form of h (see String) to b and returns the
+// resulting slice. It never returns an error.
+func (h Hash) AppendText(b []byte) ([]byte, error) {
+	c := h.Bytes()
+	var t [16]byte
+	hex.Encode(t[:], c[:])
+	return append(b, t[:]...), nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface using the
+// format described in String.
+func (h Hash) MarshalText() ([]byte, error) {
+	return h.AppendText(make([]byte, 0, 16))
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+// It accepts the same input as ParseHex.
+func (h *Hash) UnmarshalText(text []byte) error {
+	if len(text) != 16 {
+		return errors.New("xxhash: invalid Hash text length")
+	}
+	var c [8]byte
+	if _, err := hex.Decode(c[:], text); err != nil {
+		return errors.New("xxhash: invalid Hash text: " + err.Error())
+	}
+	*h = HashFromBytes(c)
+	return nil
+}
+
+// ParseHex parses s, which must consist of exactly 16 hexadecimal digits in
+// either case, as produced by String.
+func ParseHex(s string) (Hash, error) {
+	var h Hash
+	err := h.UnmarshalText([]byte(s))
+	return h, err
+}
+
+// Value implements the driver.Valuer interface. A Hash is stored in a
+// database as its text form (see String).
+func (h Hash) Value() (driver.Value, error) {
+	return h.String(), nil
+}
+
+// Scan implements the sql.Scanner interface. It accepts the text form as a
+// string or []byte (as stored by Value), the 8-byte canonical form as a
+// []byte (for binary columns), and an int64 holding the hash's bits (for
+// 64-bit integer columns). A NULL value is an error.
+func (h *Hash) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		return h.UnmarshalText([]byte(src))
+	case []byte:
+		if len(src) == 8 {
+			return h.UnmarshalBinary(src)
+		}
+		return h.UnmarshalText(src)
+	case int64:
+		*h = Hash(src)
+		return nil
+	case nil:
+		return errors.New("xxhash: cannot scan NULL into Hash")
+	}
+	return fmt.Errorf("xxhash: cannot scan %T into Hash", src)
+}
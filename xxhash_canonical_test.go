@@ -0,0 +1,101 @@
+package xxhash
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+var (
+	_ sql.Scanner   = (*Hash)(nil)
+	_ driver.Valuer = Hash(0)
+)
+
+func TestHashEncoding(t *testing.T) {
+	d := New()
+	d.WriteString("abc")
+	h := Hash(d.Sum64())
+
+	const text = "44bc2cf5ad770999"
+	if got := h.String(); got != text {
+		t.Fatalf("String: got %q; want %q", got, text)
+	}
+	if got := fmt.Sprintf("%016x", uint64(h)); got != text {
+		t.Fatalf("String differs from %%016x: %q", got)
+	}
+	b := h.Bytes()
+	if !bytes.Equal(b[:], d.Sum(nil)) {
+		t.Fatalf("Bytes: got %x; want Digest.Sum output %x", b, d.Sum(nil))
+	}
+	if got := HashFromBytes(b); got != h {
+		t.Fatalf("HashFromBytes: got %v; want %v", got, h)
+	}
+
+	for _, s := range []string{text, "44BC2CF5AD770999"} {
+		got, err := ParseHex(s)
+		if err != nil || got != h {
+			t.Fatalf("ParseHex(%q): got (%v, %v); want (%v, nil)", s, got, err, h)
+		}
+	}
+	for _, s := range []string{"", "44bc2cf5ad77099", "44bc2cf5ad7709990", "0x44bc2cf5ad7709", "44bc2cf5ad77099g"} {
+		if _, err := ParseHex(s); err == nil {
+			t.Fatalf("ParseHex(%q) succeeded", s)
+		}
+	}
+
+	bin, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h1 Hash
+	if err := h1.UnmarshalBinary(bin); err != nil || h1 != h {
+		t.Fatalf("binary round trip: got (%v, %v); want (%v, nil)", h1, err, h)
+	}
+	if got, _ := h.AppendBinary([]byte("x")); !bytes.Equal(got, append([]byte("x"), b[:]...)) {
+		t.Fatalf("AppendBinary: got %x", got)
+	}
+
+	j, err := json.Marshal(map[string]Hash{"h": h})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"h":"` + text + `"}`; string(j) != want {
+		t.Fatalf("json.Marshal: got %s; want %s", j, want)
+	}
+	var m map[string]Hash
+	if err := json.Unmarshal(j, &m); err != nil || m["h"] != h {
+		t.Fatalf("json.Unmarshal: got (%v, %v); want %v", m["h"], err, h)
+	}
+}
+
+func TestHashSQL(t *testing.T) {
+	h := Hash(0x44bc2cf5ad770999)
+	v, err := h.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "44bc2cf5ad770999" {
+		t.Fatalf("Value: got %v", v)
+	}
+	canonical := h.Bytes()
+	for _, src := range []interface{}{
+		v,
+		[]byte("44bc2cf5ad770999"),
+		canonical[:],
+		int64(h),
+	} {
+		var got Hash
+		if err := got.Scan(src); err != nil || got != h {
+			t.Fatalf("Scan(%#v): got (%v, %v); want (%v, nil)", src, got, err, h)
+		}
+	}
+	for _, src := range []interface{}{nil, 1.5, "xyz", []byte{1, 2, 3}} {
+		var got Hash
+		if err := got.Scan(src); err == nil {
+			t.Fatalf("Scan(%#v) succeeded", src)
+		}
+	}
+}
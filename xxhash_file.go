@@ -0,0 +1,99 @@
+package xxhash
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrFileChanged is returned (wrapped in an *os.PathError) by SumFile,
+// SumFileWithSeed, and Digest.WriteFile when the size of a memory-mapped
+// file changes while it is being hashed.
+var ErrFileChanged = errors.New("xxhash: file changed size while being hashed")
+
+// mmapMinSize is the smallest file that is hashed through a memory mapping.
+// For smaller files, setting up the mapping costs more than reading.
+const mmapMinSize = readBufSize
+
+// SumFile computes the 64-bit xxHash digest, with a zero seed, of the
+// contents of the named file.
+//
+// On Linux, large regular files are memory-mapped and hashed in a single
+// call, avoiding copies. Other files (pipes, devices, and so on) and other
+// platforms use buffered reads.
+//
+// When a file is memory-mapped and its size changes while it is being hashed
+// (for instance, because it is truncated), SumFile returns an error wrapping
+// ErrFileChanged rather than a hash of inconsistent contents. When a file is
+// read, SumFile hashes whatever data is read before EOF, as io.Copy would.
+func SumFile(path string) (uint64, error) {
+	return SumFileWithSeed(path, 0)
+}
+
+// SumFileWithSeed is like SumFile but uses the given seed.
+func SumFileWithSeed(path string, seed uint64) (uint64, error) {
+	var h uint64
+	_, err := hashFile(path, func(b []byte) {
+		h = Sum64WithSeed(b, seed)
+	}, func(r io.Reader) (int64, error) {
+		d := NewWithSeed(seed)
+		n, err := d.ReadFrom(r)
+		h = d.Sum64()
+		return n, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return h, nil
+}
+
+// WriteFile adds the contents of the named file to d, the same way that
+// SumFile reads it. It returns the number of bytes added. If an error
+// occurs, d is left unchanged.
+func (d *Digest) WriteFile(path string) (n int64, err error) {
+	c := *d
+	n, err = hashFile(path, func(b []byte) {
+		c.Write(b)
+	}, c.ReadFrom)
+	if err != nil {
+		return 0, err
+	}
+	*d = c
+	return n, nil
+}
+
+// hashFile opens the named file and passes its contents either to mapped,
+// as a single memory-mapped slice, or to read, as an io.Reader. It returns
+// the number of bytes hashed.
+func hashFile(path string, mapped func([]byte), read func(io.Reader) (int64, error)) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if fi.Mode().IsRegular() && fi.Size() >= mmapMinSize {
+		size := fi.Size()
+		if b, unmap, ok := mmapFile(f, size); ok {
+			faulted := hashMapped(b, mapped)
+			unmap()
+			// A fault means that the file was truncated. Also check
+			// for changes that don't cause a fault, such as growth or
+			// truncation within the last page.
+			if !faulted {
+				fi, err = f.Stat()
+				if err != nil {
+					return 0, err
+				}
+			}
+			if faulted || fi.Size() != size {
+				return 0, &os.PathError{Op: "read", Path: path, Err: ErrFileChanged}
+			}
+			return size, nil
+		}
+	}
+	return read(f)
+}
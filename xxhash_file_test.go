@@ -0,0 +1,65 @@
+package xxhash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSumFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, size := range []int{0, 1, 100, mmapMinSize - 1, mmapMinSize, 1<<20 + 7} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 31)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("f%d", size))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		h, err := SumFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := Sum64(data); h != want {
+			t.Errorf("size=%d: SumFile: got 0x%x; want 0x%x", size, h, want)
+		}
+		h, err = SumFileWithSeed(path, 123)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := Sum64WithSeed(data, 123); h != want {
+			t.Errorf("size=%d: SumFileWithSeed: got 0x%x; want 0x%x", size, h, want)
+		}
+
+		d := NewWithSeed(123)
+		d.WriteString("prefix")
+		n, err := d.WriteFile(path)
+		if err != nil || n != int64(size) {
+			t.Fatalf("size=%d: WriteFile: got (%d, %v); want (%d, nil)", size, n, err, size)
+		}
+		d.WriteString("suffix")
+		want := Sum64WithSeed([]byte("prefix"+string(data)+"suffix"), 123)
+		if got := d.Sum64(); got != want {
+			t.Errorf("size=%d: Digest.WriteFile: got 0x%x; want 0x%x", size, got, want)
+		}
+	}
+}
+
+func TestSumFileErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+	if _, err := SumFile(path); !os.IsNotExist(err) {
+		t.Fatalf("SumFile of a missing file: got error %v; want a not-exist error", err)
+	}
+
+	d := New()
+	d.WriteString("abc")
+	if _, err := d.WriteFile(path); err == nil {
+		t.Fatal("WriteFile of a missing file succeeded")
+	}
+	if got, want := d.Sum64(), Sum64String("abc"); got != want {
+		t.Fatalf("Digest changed by failed WriteFile: got 0x%x; want 0x%x", got, want)
+	}
+}
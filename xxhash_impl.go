@@ -0,0 +1,94 @@
+package xxhash
+
+import (
+	"errors"
+	"os"
+)
+
+// useGeneric selects the pure Go implementation even when the assembly
+// implementation is available. The Go functions that call the assembly
+// check it first.
+var useGeneric bool
+
+// The names of the implementations, from slowest to fastest. Each one
+// includes the ones before it: "avx2" and "avx512" use the assembly
+// implementation for everything except Sum64Batch and Sum64StringBatch.
+const (
+	implGeneric = "generic"
+	implAsm     = "asm"
+	implAVX2    = "avx2"
+	implAVX512  = "avx512"
+)
+
+func init() {
+	// Unknown or unsupported names are ignored, rather than failing at
+	// startup, so that a setting shared by several machines is harmless on
+	// the ones that lack a particular instruction set.
+	if name := os.Getenv("XXHASH_IMPL"); name != "" {
+		SetImplementation(name)
+	}
+}
+
+// Implementations returns the names of the implementations that can be
+// selected on this machine, from slowest to fastest. The list always begins
+// with "generic", the pure Go implementation. It may continue with "asm"
+// (the assembly implementation for amd64, arm64, ppc64le, riscv64, and
+// s390x), and then "avx2" and "avx512" (which add vectorized kernels for
+// Sum64Batch on amd64 CPUs that support them).
+func Implementations() []string {
+	names := []string{implGeneric}
+	if hasAsm {
+		names = append(names, implAsm)
+	}
+	if hasAVX2 {
+		names = append(names, implAVX2)
+	}
+	if hasAVX512 {
+		names = append(names, implAVX512)
+	}
+	return names
+}
+
+// Implementation returns the name of the implementation in use.
+// By default, this is the last (fastest) one in Implementations.
+func Implementation() string {
+	switch {
+	case useGeneric:
+		return implGeneric
+	case useAVX512:
+		return implAVX512
+	case useAVX2:
+		return implAVX2
+	case hasAsm:
+		return implAsm
+	}
+	return implGeneric
+}
+
+// SetImplementation selects the named implementation, which must be one of
+// those listed by Implementations, for all hashing done by this package.
+// All implementations compute the same hash values; SetImplementation is
+// meant for testing and benchmarking them, and for working around bugs.
+//
+// The implementation can also be selected by setting the XXHASH_IMPL
+// environment variable to its name. SetImplementation overrides the
+// environment variable; invalid values of XXHASH_IMPL are ignored.
+//
+// SetImplementation must not be called concurrently with any other function
+// or method of this package.
+func SetImplementation(name string) error {
+	var ok bool
+	for _, n := range Implementations() {
+		if n == name {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return errors.New("xxhash: implementation " + name + " is not available")
+	}
+	useGeneric = name == implGeneric
+	useAVX2 = hasAVX2 && (name == implAVX2 || name == implAVX512)
+	useAVX512 = hasAVX512 && name == implAVX512
+	return nil
+}
@@ -0,0 +1,116 @@
+package xxhash
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+// TestImplementations reruns the hashing tests with each implementation
+// available on this machine selected, and checks that every implementation
+// agrees with the pure Go one on inputs of every length through several
+// blocks.
+func TestImplementations(t *testing.T) {
+	defer SetImplementation(Implementation())
+
+	input := make([]byte, 300)
+	for i := range input {
+		input[i] = byte(i*17 + 9)
+	}
+	seeds := []uint64{0, 1, prime5, math.MaxUint64}
+
+	if err := SetImplementation("generic"); err != nil {
+		t.Fatal(err)
+	}
+	want := make([][]uint64, len(seeds))
+	for i, seed := range seeds {
+		want[i] = make([]uint64, len(input)+1)
+		for n := range want[i] {
+			want[i][n] = Sum64WithSeed(input[:n], seed)
+		}
+	}
+	keys := make([][]byte, len(input)+1)
+	for n := range keys {
+		keys[n] = input[:n]
+	}
+
+	for _, name := range Implementations() {
+		t.Run(name, func(t *testing.T) {
+			if err := SetImplementation(name); err != nil {
+				t.Fatal(err)
+			}
+			if got := Implementation(); got != name {
+				t.Fatalf("Implementation: got %q; want %q", got, name)
+			}
+
+			testAll(t)
+			testSum64AllLengths(t)
+			testDigestSum64(t)
+			testSmallWrites(t)
+			testTemplate(t)
+			testSum64Ints(t)
+			testSum64BatchAll(t)
+
+			for i, seed := range seeds {
+				for n, want := range want[i] {
+					if got := Sum64WithSeed(input[:n], seed); got != want {
+						t.Fatalf("Sum64WithSeed(len=%d, seed=%d): got 0x%x; want 0x%x", n, seed, got, want)
+					}
+					// Write in uneven chunks to exercise the
+					// block loop on unaligned input.
+					d := NewWithSeed(seed)
+					for b := input[:n]; len(b) > 0; {
+						c := 37
+						if c > len(b) {
+							c = len(b)
+						}
+						d.Write(b[:c])
+						b = b[c:]
+					}
+					if got := d.Sum64(); got != want {
+						t.Fatalf("Digest.Sum64(len=%d, seed=%d): got 0x%x; want 0x%x", n, seed, got, want)
+					}
+				}
+			}
+			out := make([]uint64, len(keys))
+			Sum64Batch(keys, out)
+			for n, got := range out {
+				if want := want[0][n]; got != want {
+					t.Fatalf("Sum64Batch(len=%d): got 0x%x; want 0x%x", n, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetImplementation(t *testing.T) {
+	defer SetImplementation(Implementation())
+
+	impls := Implementations()
+	if len(impls) == 0 || impls[0] != "generic" {
+		t.Fatalf("Implementations: got %q; want a list starting with \"generic\"", impls)
+	}
+	// The environment variable may have selected another implementation.
+	if _, ok := os.LookupEnv("XXHASH_IMPL"); !ok {
+		want := "generic"
+		switch {
+		case hasAVX512:
+			want = "avx512"
+		case hasAVX2:
+			want = "avx2"
+		case hasAsm:
+			want = "asm"
+		}
+		if got := Implementation(); got != want {
+			t.Errorf("Implementation: got %q; want %q by default", got, want)
+		}
+		if got := impls[len(impls)-1]; got != want {
+			t.Errorf("Implementations: got %q; want %q last", impls, want)
+		}
+	}
+	for _, name := range []string{"", "bogus", "Generic"} {
+		if err := SetImplementation(name); err == nil {
+			t.Errorf("SetImplementation(%q): got nil error", name)
+		}
+	}
+}
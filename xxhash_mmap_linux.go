@@ -0,0 +1,41 @@
+//go:build linux && !appengine
+// +build linux,!appengine
+
+package xxhash
+
+import (
+	"os"
+	"runtime/debug"
+	"syscall"
+)
+
+// mmapFile maps the first size bytes of f into memory. It reports false if
+// the file cannot be mapped, in which case the caller should read it instead.
+func mmapFile(f *os.File, size int64) (b []byte, unmap func(), ok bool) {
+	if int64(int(size)) != size {
+		return nil, nil, false // too large for the address space
+	}
+	b, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, false
+	}
+	syscall.Madvise(b, syscall.MADV_SEQUENTIAL)
+	return b, func() { syscall.Munmap(b) }, true
+}
+
+// hashMapped calls fn(b), where b is a file mapping. If the file is
+// truncated, reading the part of b past the new end of the file raises
+// SIGBUS; hashMapped recovers from the resulting fault and reports it.
+func hashMapped(b []byte, fn func([]byte)) (faulted bool) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(interface{ Addr() uintptr }); !ok {
+				panic(r)
+			}
+			faulted = true
+		}
+	}()
+	fn(b)
+	return false
+}
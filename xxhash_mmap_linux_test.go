@@ -0,0 +1,39 @@
+//go:build linux && !appengine
+// +build linux,!appengine
+
+package xxhash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashMappedTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	const size = 1 << 20
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b, unmap, ok := mmapFile(f, size)
+	if !ok {
+		t.Skip("cannot mmap test file")
+	}
+	defer unmap()
+
+	if err := f.Truncate(100); err != nil {
+		t.Fatal(err)
+	}
+	if !hashMapped(b, func(b []byte) { sink = Sum64(b) }) {
+		t.Fatal("hashMapped did not report a fault for a truncated file")
+	}
+	// The fault setting must be restored.
+	if hashMapped(b[:100], func(b []byte) { sink = Sum64(b) }) {
+		t.Fatal("hashMapped reported a fault for the untruncated part of the file")
+	}
+}
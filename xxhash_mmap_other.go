@@ -0,0 +1,15 @@
+//go:build !linux || appengine
+// +build !linux appengine
+
+package xxhash
+
+import "os"
+
+func mmapFile(f *os.File, size int64) (b []byte, unmap func(), ok bool) {
+	return nil, nil, false
+}
+
+func hashMapped(b []byte, fn func([]byte)) (faulted bool) {
+	fn(b)
+	return false
+}
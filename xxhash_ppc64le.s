@@ -0,0 +1,243 @@
+//go:build !appengine && gc && !purego
+// +build !appengine
+// +build gc
+// +build !purego
+
+#include "textflag.h"
+
+// Registers:
+#define digest	R3
+#define h	R4 // return value
+#define p	R5 // input pointer
+#define n	R6 // input length
+#define nblocks	R7 // n / 32
+#define prime1	R8
+#define prime2	R9
+#define prime3	R10
+#define prime4	R14
+#define prime5	R15
+#define v1	R16
+#define v2	R17
+#define v3	R18
+#define v4	R19
+#define x1	R22
+#define x2	R23
+#define x3	R24
+#define x4	R25
+
+#define round(acc, x) \
+	MULLD prime2, x       \
+	ADD   x, acc          \
+	ROTL  $31, acc, acc   \
+	MULLD prime1, acc
+
+// round0 performs the operation x = round(0, x).
+#define round0(x) \
+	MULLD prime2, x   \
+	ROTL  $31, x, x   \
+	MULLD prime1, x
+
+#define mergeRound(acc, x) \
+	round0(x)         \
+	XOR   x, acc      \
+	MULLD prime1, acc \
+	ADD   prime4, acc
+
+// blockLoop processes as many 32-byte blocks as possible,
+// updating v1, v2, v3, and v4. It assumes that n >= 32.
+#define blockLoop() \
+	SRD     $5, n, nblocks \
+	MOVD    nblocks, CTR   \
+	PCALIGN $16            \
+loop:                      \
+	MOVD    0(p), x1       \
+	MOVD    8(p), x2       \
+	MOVD    16(p), x3      \
+	MOVD    24(p), x4      \
+	ADD     $32, p         \
+	round(v1, x1)          \
+	round(v2, x2)          \
+	round(v3, x3)          \
+	round(v4, x4)          \
+	BDNZ    loop
+
+// mergeLanes computes h from v1, v2, v3, and v4 after the block loop.
+#define mergeLanes() \
+	ROTL $1, v1, h     \
+	ROTL $7, v2, x2    \
+	ADD  x2, h         \
+	ROTL $12, v3, x3   \
+	ADD  x3, h         \
+	ROTL $18, v4, x4   \
+	ADD  x4, h         \
+	mergeRound(h, v1)  \
+	mergeRound(h, v2)  \
+	mergeRound(h, v3)  \
+	mergeRound(h, v4)
+
+// mix8 mixes the 8-byte word x into h.
+#define mix8(x) \
+	round0(x)       \
+	XOR   x, h      \
+	ROTL  $27, h, h \
+	MULLD prime1, h \
+	ADD   prime4, h
+
+// mix1 mixes the byte x into h.
+#define mix1(x) \
+	MULLD prime5, x \
+	XOR   x, h      \
+	ROTL  $11, h, h \
+	MULLD prime1, h
+
+// tail mixes the remaining (n mod 32) bytes at p into h and finalizes it.
+// The caller must already have added the total input length to h.
+#define tail() \
+	ANDCC $16, n, x1       \
+	BEQ   try8             \
+	MOVD  0(p), x1         \
+	MOVD  8(p), x2         \
+	ADD   $16, p           \
+	mix8(x1)               \
+	mix8(x2)               \
+try8:                      \
+	ANDCC $8, n, x1        \
+	BEQ   try4             \
+	MOVD  0(p), x1         \
+	ADD   $8, p            \
+	mix8(x1)               \
+try4:                      \
+	ANDCC $4, n, x1        \
+	BEQ   try2             \
+	MOVWZ 0(p), x1         \
+	ADD   $4, p            \
+	MULLD prime1, x1       \
+	XOR   x1, h            \
+	ROTL  $23, h, h        \
+	MULLD prime2, h        \
+	ADD   prime3, h        \
+try2:                      \
+	ANDCC $2, n, x1        \
+	BEQ   try1             \
+	MOVBZ 0(p), x1         \
+	MOVBZ 1(p), x2         \
+	ADD   $2, p            \
+	mix1(x1)               \
+	mix1(x2)               \
+try1:                      \
+	ANDCC $1, n, x1        \
+	BEQ   finalize         \
+	MOVBZ 0(p), x1         \
+	mix1(x1)               \
+finalize:                  \
+	SRD   $33, h, x1       \
+	XOR   x1, h            \
+	MULLD prime2, h        \
+	SRD   $29, h, x1       \
+	XOR   x1, h            \
+	MULLD prime3, h        \
+	SRD   $32, h, x1       \
+	XOR   x1, h
+
+#define loadPrimes() \
+	MOVD ·primes+0(SB), prime1  \
+	MOVD ·primes+8(SB), prime2  \
+	MOVD ·primes+16(SB), prime3 \
+	MOVD ·primes+24(SB), prime4 \
+	MOVD ·primes+32(SB), prime5
+
+// func sum64WithSeedAsm(b []byte, seed uint64) uint64
+TEXT ·sum64WithSeedAsm(SB), NOSPLIT|NOFRAME, $0-40
+	MOVD b_base+0(FP), p
+	MOVD b_len+8(FP), n
+
+	// The seed is kept in v3, which is also its initial lane value.
+	MOVD seed+24(FP), v3
+
+	loadPrimes()
+
+	CMPU n, $32
+	BLT  noBlocks
+
+	ADD prime1, prime2, v1
+	ADD v3, v1
+	ADD v3, prime2, v2
+	SUB prime1, v3, v4
+
+	blockLoop()
+	mergeLanes()
+	BR afterBlocks
+
+noBlocks:
+	ADD v3, prime5, h
+
+afterBlocks:
+	ADD n, h
+	tail()
+
+	MOVD h, ret+32(FP)
+	RET
+
+// func writeBlocksAsm(d *Digest, b []byte) int
+TEXT ·writeBlocksAsm(SB), NOSPLIT|NOFRAME, $0-40
+	MOVD ·primes+0(SB), prime1
+	MOVD ·primes+8(SB), prime2
+
+	// Load state. Assume v[1-4] are stored contiguously.
+	MOVD d+0(FP), digest
+	MOVD 0(digest), v1
+	MOVD 8(digest), v2
+	MOVD 16(digest), v3
+	MOVD 24(digest), v4
+
+	MOVD b_base+8(FP), p
+	MOVD b_len+16(FP), n
+
+	// We don't need to check the loop condition here; this function is
+	// always called with at least one block of data to process.
+	blockLoop()
+
+	// Store updated state.
+	MOVD v1, 0(digest)
+	MOVD v2, 8(digest)
+	MOVD v3, 16(digest)
+	MOVD v4, 24(digest)
+
+	SRD  $5, n
+	SLD  $5, n
+	MOVD n, ret+32(FP)
+	RET
+
+// func digestSum64Asm(d *Digest, b []byte) uint64
+TEXT ·digestSum64Asm(SB), NOSPLIT|NOFRAME, $0-40
+	MOVD d+0(FP), digest
+	MOVD b_base+8(FP), p
+	MOVD b_len+16(FP), n
+
+	loadPrimes()
+
+	// Check whether d has processed at least one block (d.total >= 32).
+	MOVD 32(digest), x1
+	CMPU x1, $32
+	BLT  noBlocks
+
+	// Load state. Assume v[1-4] are stored contiguously.
+	MOVD 0(digest), v1
+	MOVD 8(digest), v2
+	MOVD 16(digest), v3
+	MOVD 24(digest), v4
+
+	mergeLanes()
+	BR afterBlocks
+
+noBlocks:
+	MOVD 16(digest), v3
+	ADD  v3, prime5, h
+
+afterBlocks:
+	MOVD 32(digest), x1
+	ADD  x1, h
+	tail()
+
+	MOVD h, ret+32(FP)
+	RET
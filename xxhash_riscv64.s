@@ -0,0 +1,318 @@
+//go:build !appengine && gc && !purego
+// +build !appengine
+// +build gc
+// +build !purego
+
+#include "textflag.h"
+
+// Registers:
+#define digest	X5
+#define h	X6 // return value
+#define p	X7 // input pointer
+#define n	X8 // input length
+#define end	X9 // loop end; also a loop counter in tail
+#define prime1	X10
+#define prime2	X11
+#define prime3	X12
+#define prime4	X13
+#define prime5	X14
+#define v1	X15
+#define v2	X16
+#define v3	X17
+#define v4	X18
+#define x1	X19
+#define x2	X20
+#define x3	X21
+#define x4	X22
+#define tmp	X23
+#define shr	X24 // 8 * (p mod 8), for misaligned input
+#define shl	X25 // 64 - shr
+#define wprev	X28
+#define wnext	X29
+#define aligned	X30 // p rounded down to a multiple of 8
+
+// Misaligned loads are allowed on riscv64, but many cores trap on them and
+// have the kernel or firmware emulate them, which is very slow. When p is
+// not 8-byte aligned, the loops below instead load the aligned words that
+// cover the input and shift adjacent words together. An aligned load never
+// crosses a page boundary, and each one contains at least one input byte,
+// so this never reads from an unmapped page.
+
+// rol sets dst to src rotated left by r bits.
+#define rol(r, src, dst) \
+	SRLI $(64-r), src, tmp \
+	SLLI $r, src, dst      \
+	OR   tmp, dst
+
+#define round(acc, x) \
+	MUL prime2, x         \
+	ADD x, acc            \
+	rol(31, acc, acc)     \
+	MUL prime1, acc
+
+// round0 performs the operation x = round(0, x).
+#define round0(x) \
+	MUL prime2, x   \
+	rol(31, x, x)   \
+	MUL prime1, x
+
+#define mergeRound(acc, x) \
+	round0(x)       \
+	XOR x, acc      \
+	MUL prime1, acc \
+	ADD prime4, acc
+
+// unalignedWord sets x to the 8 input bytes that start shr/8 bytes into
+// the aligned word wprev and continue into wnext.
+#define unalignedWord(x) \
+	SRL shr, wprev, x \
+	SLL shl, wnext, tmp \
+	OR  tmp, x
+
+// blockLoop processes as many 32-byte blocks as possible,
+// updating v1, v2, v3, and v4. It assumes that n >= 32.
+#define blockLoop() \
+	ADD  p, n, end              \
+	ADD  $-32, end              \
+	AND  $7, p, shr             \
+	BNEZ shr, blocksMisaligned  \
+blocks:                         \
+	MOV   0(p), x1              \
+	MOV   8(p), x2              \
+	MOV   16(p), x3             \
+	MOV   24(p), x4             \
+	ADD   $32, p                \
+	round(v1, x1)               \
+	round(v2, x2)               \
+	round(v3, x3)               \
+	round(v4, x4)               \
+	BGEU  end, p, blocks        \
+	JMP   blocksDone            \
+blocksMisaligned:               \
+	AND  $-8, p, aligned        \
+	SLLI $3, shr                \
+	NEG  shr, shl               \
+	MOV  0(aligned), wprev      \
+blocksMisalignedLoop:           \
+	MOV  8(aligned), wnext      \
+	unalignedWord(x1)           \
+	MOV  16(aligned), wprev     \
+	SRL  shr, wnext, x2         \
+	SLL  shl, wprev, tmp        \
+	OR   tmp, x2                \
+	MOV  24(aligned), wnext     \
+	unalignedWord(x3)           \
+	MOV  32(aligned), wprev     \
+	SRL  shr, wnext, x4         \
+	SLL  shl, wprev, tmp        \
+	OR   tmp, x4                \
+	ADD  $32, aligned           \
+	ADD  $32, p                 \
+	round(v1, x1)               \
+	round(v2, x2)               \
+	round(v3, x3)               \
+	round(v4, x4)               \
+	BGEU end, p, blocksMisalignedLoop \
+blocksDone:
+
+// mergeLanes computes h from v1, v2, v3, and v4 after the block loop.
+#define mergeLanes() \
+	rol(1, v1, h)      \
+	rol(7, v2, x2)     \
+	ADD x2, h          \
+	rol(12, v3, x3)    \
+	ADD x3, h          \
+	rol(18, v4, x4)    \
+	ADD x4, h          \
+	mergeRound(h, v1)  \
+	mergeRound(h, v2)  \
+	mergeRound(h, v3)  \
+	mergeRound(h, v4)
+
+// mix8 mixes the 8-byte word x into h.
+#define mix8(x) \
+	round0(x)       \
+	XOR x, h        \
+	rol(27, h, h)   \
+	MUL prime1, h   \
+	ADD prime4, h
+
+// tail mixes the remaining (n mod 32) bytes at p into h and finalizes it.
+// The caller must already have added the total input length to h.
+#define tail() \
+	AND   $31, n               \
+	SRLI  $3, n, end           \
+	BEQZ  end, try4            \
+	AND   $7, p, shr           \
+	BNEZ  shr, wordsMisaligned \
+words:                         \
+	MOV   0(p), x1             \
+	ADD   $8, p                \
+	mix8(x1)                   \
+	ADD   $-1, end             \
+	BNEZ  end, words           \
+	JMP   try4                 \
+wordsMisaligned:               \
+	AND  $-8, p, aligned       \
+	SLLI $3, shr               \
+	NEG  shr, shl              \
+	MOV  0(aligned), wprev     \
+wordsMisalignedLoop:           \
+	MOV  8(aligned), wnext     \
+	unalignedWord(x1)          \
+	MOV  wnext, wprev          \
+	ADD  $8, aligned           \
+	ADD  $8, p                 \
+	mix8(x1)                   \
+	ADD  $-1, end              \
+	BNEZ end, wordsMisalignedLoop \
+try4:                          \
+	AND   $4, n, tmp           \
+	BEQZ  tmp, try1            \
+	AND   $3, p, tmp           \
+	BNEZ  tmp, bytes4          \
+	MOVWU 0(p), x1             \
+	JMP   mix4                 \
+bytes4:                        \
+	MOVBU 0(p), x1             \
+	MOVBU 1(p), x2             \
+	MOVBU 2(p), x3             \
+	MOVBU 3(p), x4             \
+	SLLI  $8, x2               \
+	SLLI  $16, x3              \
+	SLLI  $24, x4              \
+	OR    x2, x1               \
+	OR    x4, x3               \
+	OR    x3, x1               \
+mix4:                          \
+	ADD   $4, p                \
+	MUL   prime1, x1           \
+	XOR   x1, h                \
+	rol(23, h, h)              \
+	MUL   prime2, h            \
+	ADD   prime3, h            \
+try1:                          \
+	AND   $3, n, end           \
+	BEQZ  end, finalize        \
+bytes1:                        \
+	MOVBU 0(p), x1             \
+	ADD   $1, p                \
+	MUL   prime5, x1           \
+	XOR   x1, h                \
+	rol(11, h, h)              \
+	MUL   prime1, h            \
+	ADD   $-1, end             \
+	BNEZ  end, bytes1          \
+finalize:                      \
+	SRLI $33, h, tmp           \
+	XOR  tmp, h                \
+	MUL  prime2, h             \
+	SRLI $29, h, tmp           \
+	XOR  tmp, h                \
+	MUL  prime3, h             \
+	SRLI $32, h, tmp           \
+	XOR  tmp, h
+
+#define loadPrimes() \
+	MOV ·primes+0(SB), prime1  \
+	MOV ·primes+8(SB), prime2  \
+	MOV ·primes+16(SB), prime3 \
+	MOV ·primes+24(SB), prime4 \
+	MOV ·primes+32(SB), prime5
+
+// func sum64WithSeedAsm(b []byte, seed uint64) uint64
+TEXT ·sum64WithSeedAsm(SB), NOSPLIT|NOFRAME, $0-40
+	MOV b_base+0(FP), p
+	MOV b_len+8(FP), n
+
+	// The seed is kept in v3, which is also its initial lane value.
+	MOV seed+24(FP), v3
+
+	loadPrimes()
+
+	MOV  $32, tmp
+	BLTU n, tmp, noBlocks
+
+	ADD prime1, prime2, v1
+	ADD v3, v1
+	ADD v3, prime2, v2
+	SUB prime1, v3, v4
+
+	blockLoop()
+	mergeLanes()
+	JMP afterBlocks
+
+noBlocks:
+	ADD v3, prime5, h
+
+afterBlocks:
+	ADD n, h
+	tail()
+
+	MOV h, ret+32(FP)
+	RET
+
+// func writeBlocksAsm(d *Digest, b []byte) int
+TEXT ·writeBlocksAsm(SB), NOSPLIT|NOFRAME, $0-40
+	MOV ·primes+0(SB), prime1
+	MOV ·primes+8(SB), prime2
+
+	// Load state. Assume v[1-4] are stored contiguously.
+	MOV d+0(FP), digest
+	MOV 0(digest), v1
+	MOV 8(digest), v2
+	MOV 16(digest), v3
+	MOV 24(digest), v4
+
+	MOV b_base+8(FP), p
+	MOV b_len+16(FP), n
+
+	// We don't need to check the loop condition here; this function is
+	// always called with at least one block of data to process.
+	blockLoop()
+
+	// Store updated state.
+	MOV v1, 0(digest)
+	MOV v2, 8(digest)
+	MOV v3, 16(digest)
+	MOV v4, 24(digest)
+
+	// The number of bytes written is p minus the old base pointer.
+	MOV b_base+8(FP), tmp
+	SUB tmp, p
+	MOV p, ret+32(FP)
+	RET
+
+// func digestSum64Asm(d *Digest, b []byte) uint64
+TEXT ·digestSum64Asm(SB), NOSPLIT|NOFRAME, $0-40
+	MOV d+0(FP), digest
+	MOV b_base+8(FP), p
+	MOV b_len+16(FP), n
+
+	loadPrimes()
+
+	// Check whether d has processed at least one block (d.total >= 32).
+	MOV  32(digest), x1
+	MOV  $32, tmp
+	BLTU x1, tmp, noBlocks
+
+	// Load state. Assume v[1-4] are stored contiguously.
+	MOV 0(digest), v1
+	MOV 8(digest), v2
+	MOV 16(digest), v3
+	MOV 24(digest), v4
+
+	mergeLanes()
+	JMP afterBlocks
+
+noBlocks:
+	MOV 16(digest), v3
+	ADD v3, prime5, h
+
+afterBlocks:
+	MOV 32(digest), x1
+	ADD x1, h
+	tail()
+
+	MOV h, ret+32(FP)
+	RET
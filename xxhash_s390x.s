@@ -0,0 +1,248 @@
+//go:build !appengine && gc && !purego
+// +build !appengine
+// +build gc
+// +build !purego
+
+#include "textflag.h"
+
+// s390x is big-endian, so the little-endian words of the input are read with
+// byte-reversing loads (MOVDBR and MOVWBR).
+//
+// Registers (R10 and R11 are reserved for the assembler, R13 holds g, and R14
+// and R15 are the link register and stack pointer):
+#define x	R0 // scratch; never used as an address, since R0 reads as 0 there
+#define p	R1 // input pointer
+#define n	R2 // input length
+#define h	R3 // return value
+#define digest	R3 // shares a register with h
+#define v1	R4
+#define v2	R5
+#define v3	R6
+#define v4	R7
+#define prime1	R8
+#define prime2	R9
+#define nblocks	R12 // n / 32; only used by blockLoop
+
+// After the block loop, the lanes have been merged and nblocks is zero, so
+// their registers are reused for the remaining primes.
+#define prime3	R4
+#define prime4	R12
+#define prime5	R5
+
+#define round(acc, x) \
+	MULLD prime2, x         \
+	ADD   x, acc            \
+	RLLG  $31, acc, acc     \
+	MULLD prime1, acc
+
+// round0 performs the operation x = round(0, x).
+#define round0(x) \
+	MULLD prime2, x     \
+	RLLG  $31, x, x     \
+	MULLD prime1, x
+
+#define mergeRound(acc, x) \
+	round0(x)         \
+	XOR   x, acc      \
+	MULLD prime1, acc \
+	ADD   prime4, acc
+
+// blockLoop processes as many 32-byte blocks as possible,
+// updating v1, v2, v3, and v4. It assumes that n >= 32.
+#define blockLoop() \
+	SRD    $5, n, nblocks \
+loop:                     \
+	MOVDBR 0(p), x        \
+	round(v1, x)          \
+	MOVDBR 8(p), x        \
+	round(v2, x)          \
+	MOVDBR 16(p), x       \
+	round(v3, x)          \
+	MOVDBR 24(p), x       \
+	round(v4, x)          \
+	ADD    $32, p         \
+	BRCTG  nblocks, loop
+
+// mergeLanes computes h from v1, v2, v3, and v4 after the block loop.
+// It loads prime4, which mergeRound needs.
+#define mergeLanes() \
+	MOVD ·primes+24(SB), prime4 \
+	RLLG $1, v1, h              \
+	RLLG $7, v2, x              \
+	ADD  x, h                   \
+	RLLG $12, v3, x             \
+	ADD  x, h                   \
+	RLLG $18, v4, x             \
+	ADD  x, h                   \
+	mergeRound(h, v1)           \
+	mergeRound(h, v2)           \
+	mergeRound(h, v3)           \
+	mergeRound(h, v4)
+
+// mix8 mixes the 8-byte word x into h.
+#define mix8(x) \
+	round0(x)       \
+	XOR   x, h      \
+	RLLG  $27, h, h \
+	MULLD prime1, h \
+	ADD   prime4, h
+
+// mix1 mixes the byte x into h.
+#define mix1(x) \
+	MULLD prime5, x \
+	XOR   x, h      \
+	RLLG  $11, h, h \
+	MULLD prime1, h
+
+// tail mixes the remaining (n mod 32) bytes at p into h and finalizes it.
+// The caller must already have added the total input length to h and loaded
+// prime4.
+#define tail() \
+	MOVD   ·primes+16(SB), prime3 \
+	MOVD   ·primes+32(SB), prime5 \
+	TMLL   n, $16                 \
+	BEQ    try8                   \
+	MOVDBR 0(p), x                \
+	mix8(x)                       \
+	MOVDBR 8(p), x                \
+	mix8(x)                       \
+	ADD    $16, p                 \
+try8:                             \
+	TMLL   n, $8                  \
+	BEQ    try4                   \
+	MOVDBR 0(p), x                \
+	mix8(x)                       \
+	ADD    $8, p                  \
+try4:                             \
+	TMLL   n, $4                  \
+	BEQ    try2                   \
+	MOVWBR 0(p), x                \
+	MOVWZ  x, x                   \
+	MULLD  prime1, x              \
+	XOR    x, h                   \
+	RLLG   $23, h, h              \
+	MULLD  prime2, h              \
+	ADD    prime3, h              \
+	ADD    $4, p                  \
+try2:                             \
+	TMLL   n, $2                  \
+	BEQ    try1                   \
+	MOVBZ  0(p), x                \
+	mix1(x)                       \
+	MOVBZ  1(p), x                \
+	mix1(x)                       \
+	ADD    $2, p                  \
+try1:                             \
+	TMLL   n, $1                  \
+	BEQ    finalize               \
+	MOVBZ  0(p), x                \
+	mix1(x)                       \
+finalize:                         \
+	SRD    $33, h, x              \
+	XOR    x, h                   \
+	MULLD  prime2, h              \
+	SRD    $29, h, x              \
+	XOR    x, h                   \
+	MULLD  prime3, h              \
+	SRD    $32, h, x              \
+	XOR    x, h
+
+// func sum64WithSeedAsm(b []byte, seed uint64) uint64
+TEXT ·sum64WithSeedAsm(SB), NOSPLIT|NOFRAME, $0-40
+	MOVD b_base+0(FP), p
+	MOVD b_len+8(FP), n
+
+	// The seed is kept in v3, which is also its initial lane value.
+	MOVD seed+24(FP), v3
+
+	MOVD ·primes+0(SB), prime1
+	MOVD ·primes+8(SB), prime2
+
+	CMPUBLT n, $32, noBlocks
+
+	ADD prime1, prime2, v1
+	ADD v3, v1
+	ADD v3, prime2, v2
+	SUB prime1, v3, v4
+
+	blockLoop()
+	mergeLanes()
+	BR afterBlocks
+
+noBlocks:
+	MOVD ·primes+24(SB), prime4
+	MOVD ·primes+32(SB), h
+	ADD  v3, h
+
+afterBlocks:
+	ADD n, h
+	tail()
+
+	MOVD h, ret+32(FP)
+	RET
+
+// func writeBlocksAsm(d *Digest, b []byte) int
+TEXT ·writeBlocksAsm(SB), NOSPLIT|NOFRAME, $0-40
+	MOVD ·primes+0(SB), prime1
+	MOVD ·primes+8(SB), prime2
+
+	// Load state. Assume v[1-4] are stored contiguously.
+	MOVD d+0(FP), digest
+	MOVD 0(digest), v1
+	MOVD 8(digest), v2
+	MOVD 16(digest), v3
+	MOVD 24(digest), v4
+
+	MOVD b_base+8(FP), p
+	MOVD b_len+16(FP), n
+
+	// We don't need to check the loop condition here; this function is
+	// always called with at least one block of data to process.
+	blockLoop()
+
+	// Store updated state.
+	MOVD v1, 0(digest)
+	MOVD v2, 8(digest)
+	MOVD v3, 16(digest)
+	MOVD v4, 24(digest)
+
+	AND  $-32, n
+	MOVD n, ret+32(FP)
+	RET
+
+// func digestSum64Asm(d *Digest, b []byte) uint64
+TEXT ·digestSum64Asm(SB), NOSPLIT|NOFRAME, $0-40
+	MOVD b_base+8(FP), p
+	MOVD b_len+16(FP), n
+
+	MOVD ·primes+0(SB), prime1
+	MOVD ·primes+8(SB), prime2
+
+	// Load state. Assume v[1-4] are stored contiguously.
+	MOVD d+0(FP), digest
+	MOVD 0(digest), v1
+	MOVD 8(digest), v2
+	MOVD 16(digest), v3
+	MOVD 24(digest), v4
+
+	// Check whether d has processed at least one block (d.total >= 32).
+	MOVD    32(digest), x
+	CMPUBLT x, $32, noBlocks
+
+	mergeLanes()
+	BR afterBlocks
+
+noBlocks:
+	MOVD ·primes+24(SB), prime4
+	MOVD ·primes+32(SB), h
+	ADD  v3, h
+
+afterBlocks:
+	// h has replaced the Digest pointer, so reload it (into v1, which is
+	// free once the lanes are merged) to add d.total.
+	MOVD d+0(FP), v1
+	ADD  32(v1), h
+	tail()
+
+	MOVD h, ret+32(FP)
+	RET
@@ -10,7 +10,18 @@ func Sum64String(s string) uint64 {
 	return Sum64([]byte(s))
 }
 
+// Sum64StringWithSeed computes the 64-bit xxHash digest of s using the given
+// seed.
+func Sum64StringWithSeed(s string, seed uint64) uint64 {
+	return Sum64WithSeed([]byte(s), seed)
+}
+
 // WriteString adds more data to d. It always returns len(s), nil.
 func (d *Digest) WriteString(s string) (n int, err error) {
 	return d.Write([]byte(s))
 }
+
+// Sum64String returns the hash of the template's prefix followed by suffix.
+func (t *Template) Sum64String(suffix string) uint64 {
+	return t.Sum64([]byte(suffix))
+}
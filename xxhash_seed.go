@@ -0,0 +1,146 @@
+package xxhash
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+// A Seed is a random value that selects the hash function computed by a
+// Hasher or by the Seed's own Sum64 and Sum64String methods. Two computations
+// produce the same hash values only if they use the same Seed.
+//
+// Seeds are meant for in-memory hash tables and similar structures whose keys
+// may be chosen by an attacker. Because the seed is secret and differs from
+// process to process, an attacker cannot precompute a set of keys that all
+// hash to the same value (a "hash flooding" attack), as they easily can for
+// Sum64 and Sum64String, which always use a seed of zero.
+//
+// This protection relies on the hash values staying secret. XXH64 is not a
+// cryptographic hash or MAC: an attacker who can observe hash values, or
+// anything derived from them such as the iteration order of a table, may be
+// able to recover enough information to find collisions. A Seed must not be
+// used to authenticate data.
+//
+// A Seed cannot be serialized, and its hash values should not be stored or
+// sent outside the process: they will differ in any other process.
+//
+// The zero Seed is not valid; use MakeSeed to obtain a Seed.
+type Seed struct {
+	s uint64
+}
+
+// MakeSeed returns a new random Seed, made of 8 bytes read from crypto/rand.
+// It panics if the system's secure random number generator fails.
+//
+// Seeds are independent of each other: learning one Seed (for instance by
+// recovering it from its hash values) reveals nothing about any other. Two
+// calls return different Seeds with overwhelming probability (two Seeds
+// collide with probability 2^-64), but this is not guaranteed.
+func MakeSeed() Seed {
+	var b [8]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			panic("xxhash: cannot read random seed: " + err.Error())
+		}
+		if s := binary.LittleEndian.Uint64(b[:]); s != 0 {
+			return Seed{s: s}
+		}
+	}
+}
+
+// Sum64 returns the hash of b using seed s. It is equivalent to writing b to
+// a new Hasher with seed s and calling its Sum64 method, but faster.
+// It panics if s is the zero Seed.
+func (s Seed) Sum64(b []byte) uint64 {
+	return Sum64WithSeed(b, s.value())
+}
+
+// Sum64String returns the hash of str using seed s. It is like Sum64 but may
+// avoid copying str.
+func (s Seed) Sum64String(str string) uint64 {
+	return Sum64StringWithSeed(str, s.value())
+}
+
+func (s Seed) value() uint64 {
+	if s.s == 0 {
+		panic("xxhash: use of uninitialized Seed")
+	}
+	return s.s
+}
+
+// A Hasher computes a seeded XXH64 hash of a stream of bytes, like a Digest
+// created by NewWithSeed, but using a secret Seed (see Seed for the
+// guarantees this provides). Unlike Digest, it does not reveal its seed or
+// internal state, so it cannot be marshaled.
+//
+// The zero Hasher is valid and ready to use: it chooses a seed with MakeSeed
+// the first time it is used. To compute the same hash values in several
+// Hashers, call SetSeed with the same Seed on each.
+//
+// Hasher implements hash.Hash64.
+type Hasher struct {
+	d    Digest
+	seed Seed
+}
+
+// initSeed chooses a random seed for h if it doesn't have one yet.
+func (h *Hasher) initSeed() {
+	if h.seed.s == 0 {
+		h.SetSeed(MakeSeed())
+	}
+}
+
+// Seed returns h's seed.
+func (h *Hasher) Seed() Seed {
+	h.initSeed()
+	return h.seed
+}
+
+// SetSeed sets h to use seed and resets h to its initial state.
+// It panics if seed is the zero Seed.
+func (h *Hasher) SetSeed(seed Seed) {
+	h.d.ResetWithSeed(seed.value())
+	h.seed = seed
+}
+
+// Reset discards all data written to h. It keeps h's seed.
+func (h *Hasher) Reset() {
+	h.initSeed()
+	h.d.ResetWithSeed(h.seed.s)
+}
+
+// Size always returns 8 bytes.
+func (h *Hasher) Size() int { return 8 }
+
+// BlockSize always returns 32 bytes.
+func (h *Hasher) BlockSize() int { return 32 }
+
+// Write adds more data to h. It always returns len(b), nil.
+func (h *Hasher) Write(b []byte) (n int, err error) {
+	h.initSeed()
+	return h.d.Write(b)
+}
+
+// WriteString adds more data to h. It always returns len(s), nil.
+func (h *Hasher) WriteString(s string) (n int, err error) {
+	h.initSeed()
+	return h.d.WriteString(s)
+}
+
+// WriteByte adds c to h. It always returns nil.
+func (h *Hasher) WriteByte(c byte) error {
+	h.initSeed()
+	return h.d.WriteByte(c)
+}
+
+// Sum appends the current hash to b and returns the resulting slice.
+func (h *Hasher) Sum(b []byte) []byte {
+	h.initSeed()
+	return h.d.Sum(b)
+}
+
+// Sum64 returns the current hash of the data written to h.
+func (h *Hasher) Sum64() uint64 {
+	h.initSeed()
+	return h.d.Sum64()
+}
@@ -0,0 +1,111 @@
+package xxhash
+
+import (
+	"hash"
+	"strings"
+	"testing"
+)
+
+var _ hash.Hash64 = (*Hasher)(nil)
+
+func TestMakeSeed(t *testing.T) {
+	seen := make(map[Seed]bool)
+	for i := 0; i < 1000; i++ {
+		s := MakeSeed()
+		if s.s == 0 {
+			t.Fatal("MakeSeed returned the zero Seed")
+		}
+		if seen[s] {
+			t.Fatalf("MakeSeed returned %#x twice", s.s)
+		}
+		seen[s] = true
+	}
+}
+
+func TestSeed(t *testing.T) {
+	s := MakeSeed()
+	for _, n := range []int{0, 1, 8, 31, 32, 33, 100, 1000} {
+		input := strings.Repeat("x", n)
+		want := Sum64WithSeed([]byte(input), s.s)
+		if got := s.Sum64([]byte(input)); got != want {
+			t.Fatalf("n=%d: Seed.Sum64: got %#x; want %#x", n, got, want)
+		}
+		if got := s.Sum64String(input); got != want {
+			t.Fatalf("n=%d: Seed.Sum64String: got %#x; want %#x", n, got, want)
+		}
+	}
+}
+
+func TestHasher(t *testing.T) {
+	input := []byte(strings.Repeat("abcdefghij", 10))
+
+	var h Hasher
+	h.Write(input[:3])
+	h.WriteString(string(input[3:40]))
+	h.WriteByte(input[40])
+	h.Write(input[41:])
+	seed := h.Seed()
+	want := seed.Sum64(input)
+	if got := h.Sum64(); got != want {
+		t.Fatalf("zero Hasher: got %#x; want %#x", got, want)
+	}
+	if got, want := h.Sum(nil), Hash(want).Bytes(); string(got) != string(want[:]) {
+		t.Fatalf("Sum: got %x; want %x", got, want)
+	}
+
+	h.Reset()
+	if h.Seed() != seed {
+		t.Fatal("Reset changed the seed")
+	}
+	h.Write(input)
+	if got := h.Sum64(); got != want {
+		t.Fatalf("after Reset: got %#x; want %#x", got, want)
+	}
+
+	var h2 Hasher
+	h2.Write(input)
+	if h2.Seed() == seed {
+		t.Fatal("two zero Hashers chose the same seed")
+	}
+	h2.Write([]byte("junk"))
+	h2.SetSeed(seed)
+	h2.Write(input)
+	if got := h2.Sum64(); got != want {
+		t.Fatalf("after SetSeed: got %#x; want %#x", got, want)
+	}
+}
+
+func TestZeroSeedPanics(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"Sum64":       func() { Seed{}.Sum64(nil) },
+		"Sum64String": func() { Seed{}.Sum64String("") },
+		"SetSeed":     func() { new(Hasher).SetSeed(Seed{}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic with the zero Seed", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestSeedAllocs(t *testing.T) {
+	s := MakeSeed()
+	b := make([]byte, 1000)
+	t.Run("Seed.Sum64", func(t *testing.T) {
+		testAllocs(t, func() {
+			sink = s.Sum64(b)
+		})
+	})
+	t.Run("Hasher", func(t *testing.T) {
+		testAllocs(t, func() {
+			var h Hasher
+			h.SetSeed(s)
+			h.Write(b)
+			sink = h.Sum64()
+		})
+	})
+}
@@ -0,0 +1,260 @@
+package xxhash
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrInvalidState is the error returned (possibly wrapped, for use with
+// errors.Is) by Digest.UnmarshalBinary and Digest.UnmarshalText when the
+// input is not a valid serialized Digest.
+var ErrInvalidState = errors.New("xxhash: invalid hash state")
+
+// stateError describes why a serialized Digest was rejected.
+type stateError string
+
+func (e stateError) Error() string        { return ErrInvalidState.Error() + ": " + string(e) }
+func (e stateError) Is(target error) bool { return target == ErrInvalidState }
+
+// A serialized Digest (format version 7) is laid out as follows, with all
+// integers in little-endian byte order:
+//
+//	magic    "xxh\x07"
+//	v1-v4    4 × 8 bytes
+//	total    8 bytes
+//	seed     8 bytes
+//	mem      32 bytes; the first total%32 bytes are buffered input and the
+//	         rest are zero
+//	checksum 8 bytes: Sum64 of all of the preceding bytes
+//
+// Version 6 ("xxh\x06"), which UnmarshalBinary still accepts, has the same
+// layout without the seed and the checksum.
+const (
+	magic         = "xxh\x07"
+	marshaledSize = len(magic) + 8*6 + 32 + 8
+
+	magicV6         = "xxh\x06"
+	marshaledSizeV6 = len(magicV6) + 8*5 + 32
+)
+
+// AppendBinary appends the binary form of d's state (see MarshalBinary) to
+// b and returns the extended slice. It never returns an error.
+func (d *Digest) AppendBinary(b []byte) ([]byte, error) {
+	start := len(b)
+	b = append(b, magic...)
+	v1, v2, v3, v4 := d.v1, d.v2, d.v3, d.v4
+	if !d.init {
+		v1, v2, v3, v4 = initLanes(0)
+	}
+	b = appendUint64(b, v1)
+	b = appendUint64(b, v2)
+	b = appendUint64(b, v3)
+	b = appendUint64(b, v4)
+	b = appendUint64(b, d.total)
+	b = appendUint64(b, d.seed)
+	b = append(b, d.mem[:d.n]...)
+	for i := d.n; i < len(d.mem); i++ {
+		b = append(b, 0)
+	}
+	b = appendUint64(b, Sum64(b[start:]))
+	return b, nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The state
+// includes d's seed and a checksum, and it can be restored with
+// UnmarshalBinary.
+func (d *Digest) MarshalBinary() ([]byte, error) {
+	return d.AppendBinary(make([]byte, 0, marshaledSize))
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// It accepts states produced by MarshalBinary in this and earlier versions
+// of this package. States from earlier versions do not record the seed; if
+// fewer than 32 bytes had been hashed the seed is recovered from the state,
+// and otherwise Seed reports 0.
+//
+// If b is not a valid state, UnmarshalBinary returns an error for which
+// errors.Is(err, ErrInvalidState) is true, and d is unchanged.
+func (d *Digest) UnmarshalBinary(b []byte) error {
+	var s Digest
+	switch {
+	case len(b) >= len(magic) && string(b[:len(magic)]) == magic:
+		if len(b) != marshaledSize {
+			return stateError("wrong size")
+		}
+		sum := u64(b[len(b)-8:])
+		b = b[:len(b)-8]
+		if Sum64(b) != sum {
+			return stateError("checksum mismatch")
+		}
+		b = consumeLanes(&s, b[len(magic):])
+		b, s.seed = consumeUint64(b)
+		copy(s.mem[:], b)
+	case len(b) >= len(magicV6) && string(b[:len(magicV6)]) == magicV6:
+		if len(b) != marshaledSizeV6 {
+			return stateError("wrong size")
+		}
+		b = consumeLanes(&s, b[len(magicV6):])
+		copy(s.mem[:], b)
+		if s.total < 32 {
+			if s.v1|s.v2|s.v3|s.v4 == 0 {
+				// A zero Digest that had not yet hashed a block
+				// was marshaled with zero lanes. It hashed with a
+				// zero seed.
+				s.v1, s.v2, s.v3, s.v4 = initLanes(0)
+			}
+			s.seed = s.v3
+		}
+	default:
+		return stateError("unknown format")
+	}
+	s.n = int(s.total % uint64(len(s.mem)))
+	for _, c := range s.mem[s.n:] {
+		if c != 0 {
+			return stateError("unused buffer bytes are not zero")
+		}
+	}
+	if s.total < 32 {
+		// No blocks have been hashed, so the lanes must be in their
+		// initial state.
+		if v1, v2, v3, v4 := initLanes(s.seed); s.v1 != v1 || s.v2 != v2 || s.v3 != v3 || s.v4 != v4 {
+			return stateError("inconsistent lanes")
+		}
+	}
+	s.init = true
+	*d = s
+	return nil
+}
+
+// consumeLanes reads v1-v4 and total into d.
+func consumeLanes(d *Digest, b []byte) []byte {
+	b, d.v1 = consumeUint64(b)
+	b, d.v2 = consumeUint64(b)
+	b, d.v3 = consumeUint64(b)
+	b, d.v4 = consumeUint64(b)
+	b, d.total = consumeUint64(b)
+	return b
+}
+
+// AppendText appends the text form of d's state (see MarshalText) to b and
+// returns the extended slice. It never returns an error.
+func (d *Digest) AppendText(b []byte) ([]byte, error) {
+	var buf [marshaledSize]byte
+	raw, _ := d.AppendBinary(buf[:0])
+	n := len(b)
+	enc := base64.StdEncoding
+	for i := 0; i < enc.EncodedLen(len(raw)); i++ {
+		b = append(b, 0)
+	}
+	enc.Encode(b[n:], raw)
+	return b, nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The text
+// form is the standard base64 encoding of the binary form. Because Digest
+// implements encoding.TextMarshaler, encoding/json and similar packages
+// encode a Digest as a string in this form.
+func (d *Digest) MarshalText() ([]byte, error) {
+	return d.AppendText(nil)
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. It
+// accepts the output of MarshalText and reports errors the same way as
+// UnmarshalBinary.
+func (d *Digest) UnmarshalText(text []byte) error {
+	enc := base64.StdEncoding
+	if len(text) != enc.EncodedLen(marshaledSize) {
+		return stateError("wrong size")
+	}
+	var buf [marshaledSize + 2]byte // DecodedLen rounds up
+	n, err := enc.Decode(buf[:], text)
+	if err != nil {
+		return stateError("malformed base64")
+	}
+	return d.UnmarshalBinary(buf[:n])
+}
+
+func appendUint64(b []byte, x uint64) []byte {
+	var a [8]byte
+	binary.LittleEndian.PutUint64(a[:], x)
+	return append(b, a[:]...)
+}
+
+func consumeUint64(b []byte) ([]byte, uint64) {
+	x := u64(b)
+	return b[8:], x
+}
+
+// CStateSize is the size in bytes of the reference implementation's
+// XXH64_state_t, as produced by AppendCState.
+const CStateSize = 88
+
+// AppendCState appends d's state to b in the memory layout of the reference
+// C implementation's XXH64_state_t (xxHash v0.7 and later) on a
+// little-endian platform, and returns the extended slice:
+//
+//	total_len  uint64
+//	v          [4]uint64
+//	mem64      [32]byte
+//	memsize    uint32
+//	reserved32 uint32 (zero)
+//	reserved64 uint64 (zero)
+//
+// The result can be copied into an XXH64_state_t and passed to XXH64_update
+// and XXH64_digest to continue the hash in C.
+func (d *Digest) AppendCState(b []byte) []byte {
+	v1, v2, v3, v4 := d.v1, d.v2, d.v3, d.v4
+	if !d.init {
+		v1, v2, v3, v4 = initLanes(0)
+	}
+	b = appendUint64(b, d.total)
+	b = appendUint64(b, v1)
+	b = appendUint64(b, v2)
+	b = appendUint64(b, v3)
+	b = appendUint64(b, v4)
+	b = append(b, d.mem[:d.n]...)
+	for i := d.n; i < len(d.mem); i++ {
+		b = append(b, 0)
+	}
+	var a [16]byte
+	binary.LittleEndian.PutUint32(a[:], uint32(d.n))
+	return append(b, a[:]...)
+}
+
+// UnmarshalCState sets d's state from b, which must hold an XXH64_state_t
+// in the layout described by AppendCState (for instance, the raw bytes of a
+// state produced by XXH64_update on a little-endian platform). As with
+// states from old versions of MarshalBinary, the seed is recovered only if
+// fewer than 32 bytes have been hashed; otherwise Seed reports 0.
+//
+// If b is not a valid state, UnmarshalCState returns an error for which
+// errors.Is(err, ErrInvalidState) is true, and d is unchanged.
+func (d *Digest) UnmarshalCState(b []byte) error {
+	if len(b) != CStateSize {
+		return stateError("wrong size")
+	}
+	var s Digest
+	b, s.total = consumeUint64(b)
+	b, s.v1 = consumeUint64(b)
+	b, s.v2 = consumeUint64(b)
+	b, s.v3 = consumeUint64(b)
+	b, s.v4 = consumeUint64(b)
+	memsize := binary.LittleEndian.Uint32(b[len(s.mem):])
+	if memsize >= uint32(len(s.mem)) || uint64(memsize) != s.total%uint64(len(s.mem)) {
+		return stateError("inconsistent buffer size")
+	}
+	s.n = int(memsize)
+	// The C implementation leaves stale data after the buffered bytes;
+	// only copy the live part.
+	copy(s.mem[:], b[:s.n])
+	if s.total < 32 {
+		s.seed = s.v3
+		if v1, v2, _, v4 := initLanes(s.seed); s.v1 != v1 || s.v2 != v2 || s.v4 != v4 {
+			return stateError("inconsistent lanes")
+		}
+	}
+	s.init = true
+	*d = s
+	return nil
+}
@@ -0,0 +1,459 @@
+package xxhash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMarshalSeed(t *testing.T) {
+	d := NewWithSeed(123)
+	d.WriteString("The quick brown fox jumps over the lazy dog")
+	b, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d1 Digest
+	if err := d1.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := d1.Seed(); got != 123 {
+		t.Fatalf("Seed after UnmarshalBinary: got %d; want 123", got)
+	}
+	if got, want := d1.Sum64(), d.Sum64(); got != want {
+		t.Fatalf("Sum64 after UnmarshalBinary: got 0x%x; want 0x%x", got, want)
+	}
+	d1.ResetWithSeed(d1.Seed())
+	d1.WriteString("abc")
+	if got, want := d1.Sum64(), Sum64StringWithSeed("abc", 123); got != want {
+		t.Fatalf("after ResetWithSeed(Seed()): got 0x%x; want 0x%x", got, want)
+	}
+}
+
+// These states were produced by MarshalBinary before the seed and checksum
+// were added to the format.
+var v6States = []struct {
+	state string
+	input string
+	seed  uint64
+}{
+	{
+		"7878680651b6c0adee27ea60caebd4273daeb2c27b00000000000000f435147a4e86c861" +
+			"03000000000000006162630000000000000000000000000000000000000000000000000000000000",
+		"abc",
+		123,
+	},
+	{
+		"787868069d143a8fd261f91b1f904deea28ad10b7860fec49cf5869976bc7ecc9504f879" +
+			"2b000000000000006865206c617a7920646f67000000000000000000000000000000000000000000",
+		"The quick brown fox jumps over the lazy dog",
+		0,
+	},
+}
+
+func TestUnmarshalV6(t *testing.T) {
+	for _, tt := range v6States {
+		b, err := hex.DecodeString(tt.state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d Digest
+		if err := d.UnmarshalBinary(b); err != nil {
+			t.Fatalf("input=%q: %v", tt.input, err)
+		}
+		if got, want := d.Sum64(), Sum64StringWithSeed(tt.input, tt.seed); got != want {
+			t.Fatalf("input=%q: got 0x%x; want 0x%x", tt.input, got, want)
+		}
+		if got := d.Seed(); got != tt.seed {
+			t.Fatalf("input=%q: Seed: got %d; want %d", tt.input, got, tt.seed)
+		}
+	}
+}
+
+func TestUnmarshalV6ZeroDigest(t *testing.T) {
+	// A zero Digest that had hashed fewer than 32 bytes was marshaled
+	// with zero lanes.
+	b, err := hex.DecodeString("78786806" + strings.Repeat("00", 32) +
+		"0300000000000000" + "616263" + strings.Repeat("00", 29))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Digest
+	if err := d.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.Sum64(), uint64(0x44bc2cf5ad770999); got != want {
+		t.Fatalf("Sum64: got 0x%x; want 0x%x", got, want)
+	}
+	if got := d.Seed(); got != 0 {
+		t.Fatalf("Seed: got %d; want 0", got)
+	}
+	d.WriteString("def")
+	if got, want := d.Sum64(), Sum64String("abcdef"); got != want {
+		t.Fatalf("Sum64 after more input: got 0x%x; want 0x%x", got, want)
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	d := NewWithSeed(123)
+	d.WriteString("abcdefghijklmnopqrstuvwxyz0123456789")
+	valid, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v6, err := hex.DecodeString(v6States[0].state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var invalid [][]byte
+	// Every single-byte corruption of a valid state must be caught.
+	for i := range valid {
+		b := append([]byte(nil), valid...)
+		b[i] ^= 0x10
+		invalid = append(invalid, b)
+	}
+	invalid = append(invalid,
+		nil,
+		[]byte("xxh"),
+		valid[:len(valid)-1],
+		append(valid[:len(valid):len(valid)], 0),
+		v6[:len(v6)-1],
+	)
+	// Version 6 states have no checksum, but their buffers must be
+	// zero-padded and, before the first block, their lanes must be in the
+	// initial state.
+	b := append([]byte(nil), v6...)
+	b[len(b)-1] = 1
+	invalid = append(invalid, b)
+	b = append([]byte(nil), v6...)
+	b[4] ^= 1
+	invalid = append(invalid, b)
+
+	for i, b := range invalid {
+		d1 := New()
+		d1.WriteString("xyz")
+		err := d1.UnmarshalBinary(b)
+		if !errors.Is(err, ErrInvalidState) {
+			t.Fatalf("case %d: UnmarshalBinary(%x): got error %v; want ErrInvalidState", i, b, err)
+		}
+		if got, want := d1.Sum64(), Sum64String("xyz"); got != want {
+			t.Fatalf("case %d: failed UnmarshalBinary modified the Digest", i)
+		}
+	}
+}
+
+func TestAppendBinary(t *testing.T) {
+	d := NewWithSeed(5)
+	d.WriteString("abc")
+	want, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.AppendBinary([]byte("prefix"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, append([]byte("prefix"), want...)) {
+		t.Fatalf("AppendBinary: got %x; want prefix followed by %x", got, want)
+	}
+}
+
+func TestTextMarshaling(t *testing.T) {
+	d := NewWithSeed(5)
+	d.WriteString("abcdefghijklmnopqrstuvwxyz0123456789")
+	text, err := d.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d1 Digest
+	if err := d1.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d1.Sum64(), d.Sum64(); got != want {
+		t.Fatalf("after UnmarshalText: got 0x%x; want 0x%x", got, want)
+	}
+	for _, bad := range []string{"", "!!!!", string(text[:len(text)-4]), string(text) + "AAAA"} {
+		if err := d1.UnmarshalText([]byte(bad)); !errors.Is(err, ErrInvalidState) {
+			t.Fatalf("UnmarshalText(%q): got error %v; want ErrInvalidState", bad, err)
+		}
+	}
+
+	type checkpoint struct {
+		Name   string
+		Digest *Digest
+	}
+	j, err := json.Marshal(checkpoint{"x", d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c checkpoint
+	if err := json.Unmarshal(j, &c); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Digest.Sum64(), d.Sum64(); got != want {
+		t.Fatalf("after JSON round trip: got 0x%x; want 0x%x", got, want)
+	}
+	if got := c.Digest.Seed(); got != 5 {
+		t.Fatalf("Seed after JSON round trip: got %d; want 5", got)
+	}
+}
+
+// The following functions are transcriptions of the reference
+// implementation's XXH64_reset, XXH64_update, and XXH64_digest that operate
+// on an XXH64_state_t laid out in memory as on a little-endian platform.
+// They model the C side of a hash that is handed off between Go and C.
+
+const (
+	cOffTotal   = 0
+	cOffV       = 8
+	cOffMem     = 40
+	cOffMemsize = 72
+)
+
+func cReset(seed uint64) []byte {
+	s := make([]byte, CStateSize)
+	v1, v2, v3, v4 := initLanes(seed)
+	for i, v := range []uint64{v1, v2, v3, v4} {
+		binary.LittleEndian.PutUint64(s[cOffV+8*i:], v)
+	}
+	return s
+}
+
+func cUpdate(s, input []byte) {
+	le := binary.LittleEndian
+	le.PutUint64(s[cOffTotal:], le.Uint64(s[cOffTotal:])+uint64(len(input)))
+	memsize := int(le.Uint32(s[cOffMemsize:]))
+	mem := s[cOffMem : cOffMem+32]
+	if memsize+len(input) < 32 {
+		copy(mem[memsize:], input)
+		le.PutUint32(s[cOffMemsize:], uint32(memsize+len(input)))
+		return
+	}
+	rounds := func(p []byte) {
+		for i := 0; i < 4; i++ {
+			v := le.Uint64(s[cOffV+8*i:])
+			le.PutUint64(s[cOffV+8*i:], round(v, le.Uint64(p[8*i:])))
+		}
+	}
+	if memsize > 0 {
+		copy(mem[memsize:], input[:32-memsize])
+		rounds(mem)
+		input = input[32-memsize:]
+		le.PutUint32(s[cOffMemsize:], 0)
+	}
+	for ; len(input) >= 32; input = input[32:] {
+		rounds(input)
+	}
+	if len(input) > 0 {
+		copy(mem, input)
+		le.PutUint32(s[cOffMemsize:], uint32(len(input)))
+	}
+}
+
+func cDigest(s []byte) uint64 {
+	le := binary.LittleEndian
+	total := le.Uint64(s[cOffTotal:])
+	v1 := le.Uint64(s[cOffV:])
+	v2 := le.Uint64(s[cOffV+8:])
+	v3 := le.Uint64(s[cOffV+16:])
+	v4 := le.Uint64(s[cOffV+24:])
+	var h uint64
+	if total >= 32 {
+		h = rol1(v1) + rol7(v2) + rol12(v3) + rol18(v4)
+		h = mergeRound(h, v1)
+		h = mergeRound(h, v2)
+		h = mergeRound(h, v3)
+		h = mergeRound(h, v4)
+	} else {
+		h = v3 + prime5
+	}
+	h += total
+	b := s[cOffMem : cOffMem+int(total&31)]
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= round(0, le.Uint64(b))
+		h = rol27(h)*prime1 + prime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(le.Uint32(b)) * prime1
+		h = rol23(h)*prime2 + prime3
+		b = b[4:]
+	}
+	for ; len(b) > 0; b = b[1:] {
+		h ^= uint64(b[0]) * prime5
+		h = rol11(h) * prime1
+	}
+	return Mix64(h)
+}
+
+// These states are the raw bytes of XXH64_state_t values produced by
+// XXH64_reset and XXH64_update in the reference C implementation (xxHash
+// v0.8.2, x86-64), followed by the XXH64_digest of each state. The last one
+// has stale bytes in mem64 after memsize, left over from an earlier update.
+var cStates = []struct {
+	state  string
+	writes []string
+	seed   uint64
+	digest uint64
+}{
+	{
+		"0000000000000000d6b5c0adee27ea604febd4273daeb2c200000000000000007935147a4e86c861" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"00000000000000000000000000000000",
+		nil,
+		0,
+		0xef46db3751d8e999,
+	},
+	{
+		"030000000000000051b6c0adee27ea60caebd4273daeb2c27b00000000000000f435147a4e86c861" +
+			"6162630000000000000000000000000000000000000000000000000000000000" +
+			"03000000000000000000000000000000",
+		[]string{"abc"},
+		123,
+		0x2df10692fe3004b9,
+	},
+	{
+		"2b000000000000009d143a8fd261f91b1f904deea28ad10b7860fec49cf5869976bc7ecc9504f879" +
+			"6865206c617a7920646f67000000000000000000000000000000000000000000" +
+			"0b000000000000000000000000000000",
+		[]string{"The quick brown fox jumps over the lazy dog"},
+		0,
+		0x0b242d361fda71bc,
+	},
+	{
+		"2b000000000000005563e9fc44459bdbf66311a2b3c5e54d4a043e28850d278c12cb95077f1d455b" +
+			"7475767778797a4142434438396162636465666768696a6b6c6d6e6f70717273" +
+			"0b000000000000000000000000000000",
+		[]string{"abc", "0123456789abcdefghijklmnopqrstuvwxyzABCD"},
+		0xfedcba9876543210,
+		0x26115066bd52a629,
+	},
+}
+
+func TestCStateFixtures(t *testing.T) {
+	for _, tt := range cStates {
+		s, err := hex.DecodeString(tt.state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s) != CStateSize {
+			t.Fatalf("writes=%q: fixture has %d bytes; want %d", tt.writes, len(s), CStateSize)
+		}
+		var input []byte
+		for _, w := range tt.writes {
+			input = append(input, w...)
+		}
+		if got, want := tt.digest, Sum64WithSeed(input, tt.seed); got != want {
+			t.Fatalf("writes=%q: C digest is 0x%x; Sum64WithSeed is 0x%x", tt.writes, got, want)
+		}
+
+		// The transcription of the C code used by the other tests must
+		// produce the same bytes, stale ones included.
+		c := cReset(tt.seed)
+		for _, w := range tt.writes {
+			cUpdate(c, []byte(w))
+		}
+		if !bytes.Equal(c, s) {
+			t.Fatalf("writes=%q: cUpdate state:\ngot  %x\nwant %x", tt.writes, c, s)
+		}
+
+		var d Digest
+		if err := d.UnmarshalCState(s); err != nil {
+			t.Fatalf("writes=%q: UnmarshalCState: %v", tt.writes, err)
+		}
+		if got := d.Sum64(); got != tt.digest {
+			t.Fatalf("writes=%q: Sum64 after UnmarshalCState: got 0x%x; want 0x%x", tt.writes, got, tt.digest)
+		}
+		if len(input) < 32 && d.Seed() != tt.seed {
+			t.Fatalf("writes=%q: Seed after UnmarshalCState: got %d; want %d", tt.writes, d.Seed(), tt.seed)
+		}
+		d.WriteString("xyz")
+		if got, want := d.Sum64(), Sum64WithSeed(append(input, "xyz"...), tt.seed); got != want {
+			t.Fatalf("writes=%q: Sum64 after continuing: got 0x%x; want 0x%x", tt.writes, got, want)
+		}
+
+		// AppendCState matches the C state, except that it zeroes the
+		// unused part of mem64.
+		want := append([]byte(nil), s...)
+		n := binary.LittleEndian.Uint32(s[cOffMemsize:])
+		for i := cOffMem + int(n); i < cOffMem+32; i++ {
+			want[i] = 0
+		}
+		d1 := NewWithSeed(tt.seed)
+		for _, w := range tt.writes {
+			d1.WriteString(w)
+		}
+		if got := d1.AppendCState(nil); !bytes.Equal(got, want) {
+			t.Fatalf("writes=%q: AppendCState:\ngot  %x\nwant %x", tt.writes, got, want)
+		}
+	}
+}
+
+func TestCStateHandoff(t *testing.T) {
+	input := make([]byte, 300)
+	for i := range input {
+		input[i] = byte(i*7 + 1)
+	}
+	for _, seed := range []uint64{0, 123} {
+		for _, split := range []int{0, 1, 5, 31, 32, 33, 64, 100, 299, 300} {
+			want := Sum64WithSeed(input, seed)
+
+			// Start in Go, finish in C.
+			d := NewWithSeed(seed)
+			d.Write(input[:split])
+			s := d.AppendCState(nil)
+			if len(s) != CStateSize {
+				t.Fatalf("AppendCState returned %d bytes; want %d", len(s), CStateSize)
+			}
+			cUpdate(s, input[split:])
+			if got := cDigest(s); got != want {
+				t.Fatalf("seed=%d, split=%d: Go to C: got 0x%x; want 0x%x", seed, split, got, want)
+			}
+
+			// Start in C, finish in Go.
+			s = cReset(seed)
+			cUpdate(s, input[:split])
+			var d1 Digest
+			if err := d1.UnmarshalCState(s); err != nil {
+				t.Fatalf("seed=%d, split=%d: UnmarshalCState: %v", seed, split, err)
+			}
+			d1.Write(input[split:])
+			if got := d1.Sum64(); got != want {
+				t.Fatalf("seed=%d, split=%d: C to Go: got 0x%x; want 0x%x", seed, split, got, want)
+			}
+			if split < 32 && d1.Seed() != seed {
+				t.Fatalf("seed=%d, split=%d: Seed after UnmarshalCState: got %d", seed, split, d1.Seed())
+			}
+		}
+	}
+
+	// The zero Digest exports the same state as XXH64_reset(0).
+	var d Digest
+	if got, want := d.AppendCState(nil), cReset(0); !bytes.Equal(got, want) {
+		t.Fatalf("zero Digest: got C state %x; want %x", got, want)
+	}
+}
+
+func TestUnmarshalCStateInvalid(t *testing.T) {
+	s := cReset(0)
+	cUpdate(s, []byte("abc"))
+	for _, tt := range []struct {
+		name   string
+		mutate func(s []byte) []byte
+	}{
+		{"short", func(s []byte) []byte { return s[:len(s)-1] }},
+		{"memsize too large", func(s []byte) []byte { s[cOffMemsize] = 32; return s }},
+		{"memsize mismatch", func(s []byte) []byte { s[cOffMemsize] = 4; return s }},
+		{"lanes", func(s []byte) []byte { s[cOffV] ^= 1; return s }},
+	} {
+		b := tt.mutate(append([]byte(nil), s...))
+		var d Digest
+		if err := d.UnmarshalCState(b); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("%s: got error %v; want ErrInvalidState", tt.name, err)
+		}
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestAll(t *testing.T) {
+	testAll(t)
+}
+
+func testAll(t *testing.T) {
 	// Exactly 63 characters, which exercises all code paths.
 	const s63 = "Call me Ishmael. Some years ago--never mind how long precisely-"
 	for _, tt := range []struct {
@@ -49,9 +55,7 @@ func TestAll(t *testing.T) {
 				testDigest(t, tt.input, tt.seed, chunkSize, tt.want)
 			})
 		}
-		if tt.seed == 0 {
-			t.Run(name, func(t *testing.T) { testSum(t, tt.input, tt.want) })
-		}
+		t.Run(name, func(t *testing.T) { testSum(t, tt.input, tt.seed, tt.want) })
 	}
 }
 
@@ -83,9 +87,32 @@ func testDigest(t *testing.T, input string, seed uint64, chunkSize int, want uin
 	if got := d.Sum(nil); !bytes.Equal(got, b[:]) {
 		t.Fatalf("Sum: got %v; want %v", got, b[:])
 	}
+	if seed != 0 {
+		return
+	}
+	var dz Digest // the zero value uses a zero seed
+	for i := 0; i < len(input); i += chunkSize {
+		chunk := input[i:]
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		dz.WriteString(chunk)
+	}
+	if got := dz.Sum64(); got != want {
+		t.Fatalf("zero Digest.Sum64: got 0x%x; want 0x%x", got, want)
+	}
 }
 
-func testSum(t *testing.T, input string, want uint64) {
+func testSum(t *testing.T, input string, seed uint64, want uint64) {
+	if got := Sum64WithSeed([]byte(input), seed); got != want {
+		t.Fatalf("Sum64WithSeed: got 0x%x; want 0x%x", got, want)
+	}
+	if got := Sum64StringWithSeed(input, seed); got != want {
+		t.Fatalf("Sum64StringWithSeed: got 0x%x; want 0x%x", got, want)
+	}
+	if seed != 0 {
+		return
+	}
 	if got := Sum64([]byte(input)); got != want {
 		t.Fatalf("Sum64: got 0x%x; want 0x%x", got, want)
 	}
@@ -94,6 +121,107 @@ func testSum(t *testing.T, input string, want uint64) {
 	}
 }
 
+func TestSum64AllLengths(t *testing.T) {
+	testSum64AllLengths(t)
+}
+
+func testSum64AllLengths(t *testing.T) {
+	// Check every length through the first few blocks against the Digest,
+	// whose tail handling is separate from Sum64's.
+	input := make([]byte, 200)
+	for i := range input {
+		input[i] = byte(i*13 + 5)
+	}
+	for _, seed := range []uint64{0, 1, prime5, math.MaxUint64} {
+		for n := 0; n <= len(input); n++ {
+			b := input[:n]
+			d := NewWithSeed(seed)
+			d.Write(b)
+			want := d.Sum64()
+			if got := Sum64WithSeed(b, seed); got != want {
+				t.Fatalf("Sum64WithSeed(len=%d, seed=%d): got 0x%x; want 0x%x", n, seed, got, want)
+			}
+			if got := Sum64StringWithSeed(string(b), seed); got != want {
+				t.Fatalf("Sum64StringWithSeed(len=%d, seed=%d): got 0x%x; want 0x%x", n, seed, got, want)
+			}
+			if seed != 0 {
+				continue
+			}
+			if got := Sum64(b); got != want {
+				t.Fatalf("Sum64(len=%d): got 0x%x; want 0x%x", n, got, want)
+			}
+		}
+	}
+}
+
+func TestDigestSum64(t *testing.T) {
+	// Test the assembly version, if there is one, even if XXHASH_IMPL
+	// selected another implementation.
+	if hasAsm {
+		defer SetImplementation(Implementation())
+		if err := SetImplementation(implAsm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testDigestSum64(t)
+}
+
+// testDigestSum64 compares digestSum64, which may be implemented in
+// assembly, with the Go implementation for states before and after the
+// first block.
+func testDigestSum64(t *testing.T) {
+	input := make([]byte, 200)
+	for i := range input {
+		input[i] = byte(i*11 + 3)
+	}
+	for _, seed := range []uint64{0, 1, prime5, math.MaxUint64} {
+		for n := 0; n <= len(input); n++ {
+			d := NewWithSeed(seed)
+			d.Write(input[:n])
+			tail := d.mem[:d.n]
+			if got, want := digestSum64(d, tail), digestSum64Generic(d, tail); got != want {
+				t.Fatalf("len=%d, seed=%d: got 0x%x; want 0x%x", n, seed, got, want)
+			}
+			if seed != 0 {
+				continue
+			}
+			var dz Digest
+			dz.Write(input[:n])
+			tail = dz.mem[:dz.n]
+			if got, want := digestSum64(&dz, tail), digestSum64Generic(&dz, tail); got != want {
+				t.Fatalf("zero Digest, len=%d: got 0x%x; want 0x%x", n, got, want)
+			}
+		}
+	}
+}
+
+func TestSmallWrites(t *testing.T) {
+	testSmallWrites(t)
+}
+
+func testSmallWrites(t *testing.T) {
+	input := make([]byte, 100)
+	for i := range input {
+		input[i] = byte(i*7 + 1)
+	}
+	// Write a prefix, then a write of each small size, then the rest,
+	// checking that the buffered bytes are exactly the input.
+	for off := 0; off < 40; off++ {
+		for n := 0; n < 32; n++ {
+			d := New()
+			d.Write(input[:off])
+			d.Write(input[off : off+n])
+			if got, want := d.mem[:d.n], input[(off+n)&^31:off+n]; !bytes.Equal(got, want) {
+				t.Fatalf("off=%d, n=%d: buffered %x; want %x", off, n, got, want)
+			}
+			d.Write(input[off+n:])
+			if got, want := d.Sum64(), Sum64(input); got != want {
+				t.Fatalf("off=%d, n=%d: got 0x%x; want 0x%x", off, n, got, want)
+			}
+		}
+	}
+}
+
 func TestReset(t *testing.T) {
 	parts := []string{"The quic", "k br", "o", "wn fox jumps", " ov", "er the lazy ", "dog."}
 	d := New()
@@ -128,6 +256,26 @@ func TestResetWithSeed(t *testing.T) {
 	}
 }
 
+func TestZeroValueMarshaling(t *testing.T) {
+	for _, input := range []string{"", "abc"} {
+		var d Digest
+		d.WriteString(input)
+		got, err := d.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d1 := New()
+		d1.WriteString(input)
+		want, err := d1.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("input=%q: zero Digest marshaled to %x; want %x", input, got, want)
+		}
+	}
+}
+
 func TestBinaryMarshaling(t *testing.T) {
 	d := New()
 	d.WriteString("abc")
@@ -165,6 +313,193 @@ func TestBinaryMarshaling(t *testing.T) {
 	}
 }
 
+func TestClone(t *testing.T) {
+	input := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 3)
+	d := NewWithSeed(123)
+	d.WriteString(input[:50])
+	c := d.Clone()
+	d.WriteString(input[50:])
+	c.WriteString("abc")
+	if got, want := d.Sum64(), Sum64StringWithSeed(input, 123); got != want {
+		t.Fatalf("original: got 0x%x; want 0x%x", got, want)
+	}
+	if got, want := c.Sum64(), Sum64StringWithSeed(input[:50]+"abc", 123); got != want {
+		t.Fatalf("clone: got 0x%x; want 0x%x", got, want)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	testTemplate(t)
+}
+
+func testTemplate(t *testing.T) {
+	input := make([]byte, 200)
+	for i := range input {
+		input[i] = byte(i * 7)
+	}
+	for _, seed := range []uint64{0, 123} {
+		for prefixLen := 0; prefixLen <= 70; prefixLen++ {
+			prefix := input[:prefixLen]
+			d := NewWithSeed(seed)
+			d.Write(prefix)
+			tmpl := d.Template()
+			d.Write([]byte("ignored")) // must not affect tmpl
+			for suffixLen := 0; suffixLen <= 100; suffixLen++ {
+				suffix := input[prefixLen : prefixLen+suffixLen]
+				want := Sum64WithSeed(input[:prefixLen+suffixLen], seed)
+				if got := tmpl.Sum64(suffix); got != want {
+					t.Fatalf("seed=%d, prefix=%d, suffix=%d: Template.Sum64: got 0x%x; want 0x%x",
+						seed, prefixLen, suffixLen, got, want)
+				}
+				if got := tmpl.Sum64String(string(suffix)); got != want {
+					t.Fatalf("seed=%d, prefix=%d, suffix=%d: Template.Sum64String: got 0x%x; want 0x%x",
+						seed, prefixLen, suffixLen, got, want)
+				}
+			}
+		}
+	}
+
+	// The zero Digest's lanes are initialized lazily; a Template taken
+	// before any full block has been written must still be correct.
+	var d Digest
+	d.WriteString("abc")
+	tmpl := d.Template()
+	suffix := strings.Repeat("x", 100)
+	if got, want := tmpl.Sum64String(suffix), Sum64String("abc"+suffix); got != want {
+		t.Fatalf("zero Digest Template: got 0x%x; want 0x%x", got, want)
+	}
+}
+
+func TestTypedWrites(t *testing.T) {
+	var _ io.ByteWriter = (*Digest)(nil)
+
+	// Start at every offset within the 32-byte buffer so that each write
+	// hits both the buffered and the block-crossing paths.
+	for prefixLen := 0; prefixLen < 40; prefixLen++ {
+		prefix := strings.Repeat("p", prefixLen)
+		var want []byte
+		want = append(want, prefix...)
+		d := New()
+		d.WriteString(prefix)
+		for i := 0; i < 3; i++ {
+			d.WriteByte(0xab)
+			want = append(want, 0xab)
+			d.WriteUint16(0x1234)
+			want = append(want, 0x34, 0x12)
+			d.WriteUint32(0xdeadbeef)
+			want = append(want, 0xef, 0xbe, 0xad, 0xde)
+			d.WriteUint64(0x0102030405060708)
+			want = append(want, 8, 7, 6, 5, 4, 3, 2, 1)
+			d.WriteFloat64(-1.5)
+			want = append(want, 0, 0, 0, 0, 0, 0, 0xf8, 0xbf)
+			d.WriteStringPrefixed("xyz")
+			want = append(want, 3, 0, 0, 0, 0, 0, 0, 0, 'x', 'y', 'z')
+		}
+		if got, want := d.Sum64(), Sum64(want); got != want {
+			t.Fatalf("prefixLen=%d: got 0x%x; want 0x%x", prefixLen, got, want)
+		}
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	var _ io.ReaderFrom = (*Digest)(nil)
+
+	input := make([]byte, 200e3)
+	for i := range input {
+		input[i] = byte(i * 13)
+	}
+	for _, tt := range []struct {
+		name string
+		r    func(io.Reader) io.Reader
+	}{
+		{"plain", func(r io.Reader) io.Reader { return r }},
+		{"OneByteReader", iotest.OneByteReader},
+		{"HalfReader", iotest.HalfReader},
+		{"DataErrReader", iotest.DataErrReader},
+	} {
+		for _, n := range []int{0, 1, 31, 32, 33, 1000, readBufSize - 1, readBufSize, 3*readBufSize + 7} {
+			for _, prefixLen := range []int{0, 5, 32, 45} {
+				if tt.name == "OneByteReader" && n > 1000 {
+					continue
+				}
+				want := Sum64WithSeed(input[:prefixLen+n], 123)
+				d := NewWithSeed(123)
+				d.Write(input[:prefixLen])
+				got, err := d.ReadFrom(tt.r(bytes.NewReader(input[prefixLen : prefixLen+n])))
+				if err != nil || got != int64(n) {
+					t.Fatalf("%s: n=%d, prefixLen=%d: ReadFrom: got (%d, %v); want (%d, nil)",
+						tt.name, n, prefixLen, got, err, n)
+				}
+				if got := d.Sum64(); got != want {
+					t.Fatalf("%s: n=%d, prefixLen=%d: got 0x%x; want 0x%x", tt.name, n, prefixLen, got, want)
+				}
+				// Writes after ReadFrom must continue from the same state.
+				d.Write(input[:10])
+				if got, want := d.Sum64(), Sum64WithSeed(append(input[:prefixLen+n:prefixLen+n], input[:10]...), 123); got != want {
+					t.Fatalf("%s: n=%d, prefixLen=%d: after Write: got 0x%x; want 0x%x", tt.name, n, prefixLen, got, want)
+				}
+			}
+		}
+	}
+
+	h, err := SumReader(bytes.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Sum64(input); h != want {
+		t.Fatalf("SumReader: got 0x%x; want 0x%x", h, want)
+	}
+
+	_, err = SumReader(iotest.TimeoutReader(bytes.NewReader(input)))
+	if err != iotest.ErrTimeout {
+		t.Fatalf("SumReader: got error %v; want %v", err, iotest.ErrTimeout)
+	}
+}
+
+func TestSum64Ints(t *testing.T) {
+	testSum64Ints(t)
+}
+
+func testSum64Ints(t *testing.T) {
+	for _, seed := range []uint64{0, 123, math.MaxUint64} {
+		for _, x := range []uint64{0, 1, 0xff, 0x1234567890abcdef, math.MaxUint32, math.MaxUint64} {
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], x)
+			want := Sum64WithSeed(b[:], seed)
+			if got := Sum64Uint64WithSeed(x, seed); got != want {
+				t.Errorf("Sum64Uint64WithSeed(0x%x, %d): got 0x%x; want 0x%x", x, seed, got, want)
+			}
+			if seed == 0 {
+				if got := Sum64Uint64(x); got != want {
+					t.Errorf("Sum64Uint64(0x%x): got 0x%x; want 0x%x", x, got, want)
+				}
+			}
+
+			x32 := uint32(x)
+			want = Sum64WithSeed(b[:4], seed)
+			if got := Sum64Uint32WithSeed(x32, seed); got != want {
+				t.Errorf("Sum64Uint32WithSeed(0x%x, %d): got 0x%x; want 0x%x", x32, seed, got, want)
+			}
+			if seed == 0 {
+				if got := Sum64Uint32(x32); got != want {
+					t.Errorf("Sum64Uint32(0x%x): got 0x%x; want 0x%x", x32, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestMix64(t *testing.T) {
+	// Mix64 is the final step of every XXH64 hash. For the empty input with
+	// a zero seed, the state before it is just prime5.
+	if got, want := Mix64(prime5), Sum64(nil); got != want {
+		t.Fatalf("Mix64(prime5): got 0x%x; want 0x%x", got, want)
+	}
+	if got := Mix64(0); got != 0 {
+		t.Fatalf("Mix64(0): got 0x%x; want 0", got)
+	}
+}
+
 var sink uint64
 
 func TestAllocs(t *testing.T) {
@@ -177,6 +512,11 @@ func TestAllocs(t *testing.T) {
 			sink = Sum64([]byte(shortStr))
 		})
 	})
+	t.Run("Sum64WithSeed", func(t *testing.T) {
+		testAllocs(t, func() {
+			sink = Sum64WithSeed([]byte(shortStr), 123)
+		})
+	})
 	// Creating and using a Digest shouldn't allocate because its methods
 	// shouldn't make it escape. (A previous version of New returned a
 	// hash.Hash64 which forces an allocation.)
@@ -188,6 +528,37 @@ func TestAllocs(t *testing.T) {
 			sink = d.Sum64()
 		})
 	})
+	t.Run("Digest typed writes", func(t *testing.T) {
+		testAllocs(t, func() {
+			d := New()
+			for i := 0; i < 10; i++ {
+				d.WriteByte(1)
+				d.WriteUint16(2)
+				d.WriteUint32(3)
+				d.WriteUint64(4)
+				d.WriteFloat64(5)
+				d.WriteStringPrefixed("six")
+			}
+			sink = d.Sum64()
+		})
+	})
+	t.Run("SumReader", func(t *testing.T) {
+		b := []byte(strings.Repeat("a", 1000))
+		r := bytes.NewReader(b)
+		testAllocs(t, func() {
+			r.Reset(b)
+			sink, _ = SumReader(r)
+		})
+	})
+	t.Run("Template.Sum64", func(t *testing.T) {
+		d := New()
+		d.WriteString("some/long/namespace/")
+		tmpl := d.Template()
+		b := []byte(strings.Repeat("a", 100))
+		testAllocs(t, func() {
+			sink = tmpl.Sum64(b)
+		})
+	})
 }
 
 func testAllocs(t *testing.T, fn func()) {
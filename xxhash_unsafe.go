@@ -40,6 +40,14 @@ func Sum64String(s string) uint64 {
 	return Sum64(b)
 }
 
+// Sum64StringWithSeed computes the 64-bit xxHash digest of s using the given
+// seed. It may be faster than Sum64WithSeed([]byte(s), seed) by avoiding a
+// copy.
+func Sum64StringWithSeed(s string, seed uint64) uint64 {
+	b := *(*[]byte)(unsafe.Pointer(&sliceHeader{s, len(s)}))
+	return Sum64WithSeed(b, seed)
+}
+
 // WriteString adds more data to d. It always returns len(s), nil.
 // It may be faster than Write([]byte(s)) by avoiding a copy.
 func (d *Digest) WriteString(s string) (n int, err error) {
@@ -50,6 +58,13 @@ func (d *Digest) WriteString(s string) (n int, err error) {
 	return len(s), nil
 }
 
+// Sum64String returns the hash of the template's prefix followed by suffix.
+// It may be faster than Sum64([]byte(suffix)) by avoiding a copy.
+func (t *Template) Sum64String(suffix string) uint64 {
+	b := *(*[]byte)(unsafe.Pointer(&sliceHeader{suffix, len(suffix)}))
+	return t.Sum64(b)
+}
+
 // sliceHeader is similar to reflect.SliceHeader, but it assumes that the layout
 // of the first two words is the same as the layout of a string.
 type sliceHeader struct {
@@ -4,6 +4,7 @@
 package xxhash
 
 import (
+	"math/bits"
 	"os/exec"
 	"sort"
 	"strings"
@@ -17,6 +18,11 @@ func TestStringAllocs(t *testing.T) {
 			sink = Sum64String(longStr)
 		})
 	})
+	t.Run("Sum64StringWithSeed", func(t *testing.T) {
+		testAllocs(t, func() {
+			sink = Sum64StringWithSeed(longStr, 123)
+		})
+	})
 	t.Run("Digest.WriteString", func(t *testing.T) {
 		testAllocs(t, func() {
 			d := New()
@@ -24,14 +30,42 @@ func TestStringAllocs(t *testing.T) {
 			sink = d.Sum64()
 		})
 	})
+	t.Run("Template.Sum64String", func(t *testing.T) {
+		tmpl := New().Template()
+		testAllocs(t, func() {
+			sink = tmpl.Sum64String(longStr)
+		})
+	})
+	t.Run("Seed.Sum64String", func(t *testing.T) {
+		seed := MakeSeed()
+		testAllocs(t, func() {
+			sink = seed.Sum64String(longStr)
+		})
+	})
 }
 
 // This test is inspired by the Go runtime tests in https://go.dev/cl/57410.
 // It asserts that certain important functions may be inlined.
 func TestInlining(t *testing.T) {
 	funcs := map[string]struct{}{
-		"Sum64String":           {},
-		"(*Digest).WriteString": {},
+		"Sum64String":             {},
+		"Sum64StringWithSeed":     {},
+		"(*Digest).WriteString":   {},
+		"(*Template).Sum64String": {},
+		"Seed.Sum64":              {},
+		"Mix64":                   {},
+	}
+	if bits.UintSize == 64 {
+		// The 64-bit multiplies are too expensive to inline on 32-bit
+		// platforms.
+		for _, fn := range []string{
+			"Sum64Uint64",
+			"Sum64Uint64WithSeed",
+			"Sum64Uint32",
+			"Sum64Uint32WithSeed",
+		} {
+			funcs[fn] = struct{}{}
+		}
 	}
 
 	cmd := exec.Command("go", "test", "-gcflags=-m", "-run", "xxxx")
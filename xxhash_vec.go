@@ -0,0 +1,61 @@
+package xxhash
+
+// Sum64Vec computes the 64-bit xxHash digest, with a zero seed, of the
+// concatenation of bufs, such as a net.Buffers. It returns the same value as
+// Sum64 of the concatenated bytes, without concatenating them.
+//
+// Whole 32-byte blocks are hashed directly out of each buffer by the same
+// (assembly, where available) block loop as Sum64; only the few bytes of a
+// block that straddles a buffer boundary are copied.
+func Sum64Vec(bufs [][]byte) uint64 {
+	if len(bufs) == 1 {
+		return Sum64(bufs[0])
+	}
+	var d Digest
+	d.WriteVec(bufs)
+	return d.Sum64()
+}
+
+// Sum64Strings is like Sum64Vec but hashes the concatenation of strs. For
+// example, Sum64Strings(prefix, key, suffix) is the digest of
+// prefix+key+suffix.
+func Sum64Strings(strs ...string) uint64 {
+	if len(strs) == 1 {
+		return Sum64String(strs[0])
+	}
+	var d Digest
+	for _, s := range strs {
+		d.WriteString(s)
+	}
+	return d.Sum64()
+}
+
+// WriteVec adds the concatenation of bufs to d, as if by calling Write on
+// each buffer in turn. It always returns the total length of bufs, nil.
+//
+// Whole blocks are hashed directly out of each buffer; only a block that
+// straddles the boundary between two buffers is assembled in d's buffer.
+func (d *Digest) WriteVec(bufs [][]byte) (n int, err error) {
+	if !d.init {
+		d.v1, d.v2, d.v3, d.v4 = initLanes(0)
+		d.init = true
+	}
+	for _, b := range bufs {
+		n += len(b)
+		d.total += uint64(len(b))
+		if d.n > 0 {
+			c := copy(d.mem[d.n:], b)
+			d.n += c
+			if d.n < len(d.mem) {
+				continue
+			}
+			d.writeMem()
+			b = b[c:]
+		}
+		if len(b) >= len(d.mem) {
+			b = b[writeBlocks(d, b):]
+		}
+		d.n = copy(d.mem[:], b)
+	}
+	return n, nil
+}
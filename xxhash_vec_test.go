@@ -0,0 +1,89 @@
+package xxhash
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// randomSplit splits b into a random number of pieces, some of them empty.
+func randomSplit(r *rand.Rand, b []byte) [][]byte {
+	var bufs [][]byte
+	for len(b) > 0 {
+		n := r.Intn(len(b) + 1)
+		if r.Intn(4) == 0 {
+			n = r.Intn(40)
+			if n > len(b) {
+				n = len(b)
+			}
+		}
+		bufs = append(bufs, b[:n])
+		b = b[n:]
+	}
+	if r.Intn(2) == 0 {
+		bufs = append(bufs, nil)
+	}
+	return bufs
+}
+
+func TestSum64Vec(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	input := make([]byte, 300)
+	r.Read(input)
+	for n := 0; n <= len(input); n++ {
+		want := Sum64(input[:n])
+		for i := 0; i < 10; i++ {
+			bufs := randomSplit(r, input[:n])
+			if got := Sum64Vec(bufs); got != want {
+				t.Fatalf("Sum64Vec(%d pieces of %d bytes): got %#x; want %#x", len(bufs), n, got, want)
+			}
+			strs := make([]string, len(bufs))
+			for j, b := range bufs {
+				strs[j] = string(b)
+			}
+			if got := Sum64Strings(strs...); got != want {
+				t.Fatalf("Sum64Strings(%d pieces of %d bytes): got %#x; want %#x", len(strs), n, got, want)
+			}
+
+			// WriteVec continues from an arbitrary state.
+			split := r.Intn(n + 1)
+			var d Digest
+			d.Write(input[:split])
+			nw, err := d.WriteVec(randomSplit(r, input[split:n]))
+			if nw != n-split || err != nil {
+				t.Fatalf("WriteVec: got (%d, %v); want (%d, nil)", nw, err, n-split)
+			}
+			if got := d.Sum64(); got != want {
+				t.Fatalf("WriteVec after %d of %d bytes: got %#x; want %#x", split, n, got, want)
+			}
+
+			d1 := NewWithSeed(prime1)
+			d1.WriteVec(randomSplit(r, input[:n]))
+			if got, want := d1.Sum64(), Sum64WithSeed(input[:n], prime1); got != want {
+				t.Fatalf("WriteVec of %d bytes with a seed: got %#x; want %#x", n, got, want)
+			}
+		}
+	}
+	if got, want := Sum64Vec(nil), Sum64(nil); got != want {
+		t.Fatalf("Sum64Vec(nil): got %#x; want %#x", got, want)
+	}
+	if got, want := Sum64Strings(), Sum64(nil); got != want {
+		t.Fatalf("Sum64Strings(): got %#x; want %#x", got, want)
+	}
+}
+
+func TestSum64VecAllocs(t *testing.T) {
+	bufs := bytes.SplitAfter(bytes.Repeat([]byte("abcdefghijklm"), 100), []byte("m"))
+	prefix, suffix := strings.Repeat("p", 50), strings.Repeat("s", 50)
+	t.Run("Sum64Vec", func(t *testing.T) {
+		testAllocs(t, func() {
+			sink = Sum64Vec(bufs)
+		})
+	})
+	t.Run("Sum64Strings", func(t *testing.T) {
+		testAllocs(t, func() {
+			sink = Sum64Strings(prefix, "key", suffix)
+		})
+	})
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/cespare/xxhash/v2"
@@ -17,17 +16,13 @@ If no filenames are provided or only - is given, input is read from stdin.
 		os.Exit(1)
 	}
 	if len(os.Args) < 2 || len(os.Args) == 2 && os.Args[1] == "-" {
-		printHash(os.Stdin, "-")
+		h, err := xxhash.SumReader(os.Stdin)
+		printHash(h, err, "-")
 		return
 	}
 	for _, path := range os.Args[1:] {
-		f, err := os.Open(path)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			continue
-		}
-		printHash(f, path)
-		f.Close()
+		h, err := xxhash.SumFile(path)
+		printHash(h, err, path)
 	}
 }
 
@@ -40,11 +35,10 @@ func contains(ss []string, s string) bool {
 	return false
 }
 
-func printHash(r io.Reader, name string) {
-	h := xxhash.New()
-	if _, err := io.Copy(h, r); err != nil {
+func printHash(h uint64, err error, name string) {
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return
 	}
-	fmt.Printf("%016x  %s\n", h.Sum64(), name)
+	fmt.Printf("%016x  %s\n", h, name)
 }